@@ -6,7 +6,7 @@ import (
 )
 
 func ReportError(line int, where, message string) {
-	fmt.Printf("line %d Error at %s: %s \n", line, where, message)
+	fmt.Fprintf(os.Stderr, "line %d Error at %s: %s \n", line, where, message)
 }
 
 func ReportAndExit(line int, where, message string) {
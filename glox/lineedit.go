@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// lineReader reads the lines of repl entries.
+type lineReader interface {
+	readLine(prompt string) (string, bool)
+}
+
+// plainReader reads lines from input that is not a terminal, such as a pipe.
+type plainReader struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func (p *plainReader) readLine(prompt string) (string, bool) {
+	fmt.Fprint(p.out, prompt)
+	if !p.in.Scan() {
+		return "", false
+	}
+	return p.in.Text(), true
+}
+
+// lineEditor reads lines from a terminal one key at a time, so the cursor
+// can move within the line and earlier entries can be recalled:
+//
+//	left, right, ctrl-b, ctrl-f  move the cursor
+//	home, end, ctrl-a, ctrl-e    jump to the start or end of the line
+//	backspace, delete            delete before or under the cursor
+//	ctrl-u, ctrl-k               delete to the start or end of the line
+//	up, down                     step through the history, an entry of several
+//	                             lines showing its line breaks as ↵
+//	ctrl-c                       drop the line, ctrl-d on an empty line quits
+type lineEditor struct {
+	in  *bufio.Reader
+	out io.Writer
+	// history is the repl's, newest entry last
+	history *[]string
+	// raw puts the terminal in raw mode and returns how to undo it
+	raw func() (restore func(), err error)
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyCtrlK     = 11
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+func (e *lineEditor) readLine(prompt string) (string, bool) {
+	if e.raw != nil {
+		restore, err := e.raw()
+		if err == nil {
+			defer restore()
+		}
+	}
+
+	line := []rune{}
+	pos := 0
+	// recalled is how far back in the history the line came from
+	recalled := 0
+	draft := ""
+
+	e.redraw(prompt, line, pos)
+	for {
+		key, _, err := e.in.ReadRune()
+		if err != nil {
+			fmt.Fprint(e.out, "\r\n")
+			return string(line), len(line) > 0
+		}
+
+		switch key {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(line), true
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", true
+		case keyCtrlD:
+			if len(line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", false
+			}
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+		case keyBackspace, keyDelete:
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case keyCtrlA:
+			pos = 0
+		case keyCtrlE:
+			pos = len(line)
+		case keyCtrlB:
+			pos = max(pos-1, 0)
+		case keyCtrlF:
+			pos = min(pos+1, len(line))
+		case keyCtrlK:
+			line = line[:pos]
+		case keyCtrlU:
+			line = line[pos:]
+			pos = 0
+		case keyEscape:
+			switch e.escape() {
+			case 'A':
+				if recalled < len(*e.history) {
+					if recalled == 0 {
+						draft = string(line)
+					}
+					recalled++
+					line = e.recall(recalled, draft)
+					pos = len(line)
+				}
+			case 'B':
+				if recalled > 0 {
+					recalled--
+					line = e.recall(recalled, draft)
+					pos = len(line)
+				}
+			case 'C':
+				pos = min(pos+1, len(line))
+			case 'D':
+				pos = max(pos-1, 0)
+			case 'H':
+				pos = 0
+			case 'F':
+				pos = len(line)
+			case '~':
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+				}
+			}
+		default:
+			if key >= ' ' {
+				line = append(line[:pos], append([]rune{key}, line[pos:]...)...)
+				pos++
+			}
+		}
+		e.redraw(prompt, line, pos)
+	}
+}
+
+// escape reads the rest of an escape sequence and returns its final
+// letter: A-D for the arrows, H and F for home and end. The delete key,
+// ESC [ 3 ~, is returned as '~'.
+func (e *lineEditor) escape() rune {
+	if next, _, err := e.in.ReadRune(); err != nil || (next != '[' && next != 'O') {
+		return 0
+	}
+
+	digits := ""
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0
+		}
+		if r >= '0' && r <= '9' {
+			digits += string(r)
+			continue
+		}
+		if r != '~' {
+			return r
+		}
+		switch digits {
+		case "1", "7":
+			return 'H'
+		case "4", "8":
+			return 'F'
+		case "3":
+			return '~'
+		}
+		return 0
+	}
+}
+
+// recall returns the entry n steps back in the history, or the line being
+// typed for n = 0. The line breaks of an entry are kept, so one with a //
+// comment still means the same when it is run again.
+func (e *lineEditor) recall(n int, draft string) []rune {
+	if n == 0 {
+		return []rune(draft)
+	}
+	return []rune((*e.history)[len(*e.history)-n])
+}
+
+// redraw shows line breaks as ↵, one column wide like every other rune.
+func (e *lineEditor) redraw(prompt string, line []rune, pos int) {
+	shown := strings.ReplaceAll(string(line), "\n", "↵")
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, shown)
+	if back := len(line) - pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
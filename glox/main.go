@@ -1,108 +1,542 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/Martin-Martinez4/crafting-interpreters/glox/parser"
 	"github.com/Martin-Martinez4/crafting-interpreters/glox/scanner"
 	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/vm"
 )
 
-func main() {
+// exit codes follow sysexits.h
+const (
+	EX_OK       = 0
+	EX_USAGE    = 64
+	EX_DATAERR  = 65
+	EX_NOINPUT  = 66
+	EX_SOFTWARE = 70
+	EX_IOERR    = 74
+)
 
-	// expression := ast.Binary{
-	// 	Left: ast.Unary{
-	// 		Operator: *token.NewToken(token.MINUS, "-", nil, 1),
-	// 		Right: ast.Literal{
-	// 			Value: 123,
-	// 		},
-	// 	},
-	// 	Operator: *token.NewToken(token.STAR, "*", nil, 1),
-	// 	Right: ast.Grouping{
-	// 		Expression: ast.Literal{Value: 45.67},
-	// 	},
-	// }
-
-	minusOp := token.NewToken(token.MINUS, "-", nil, 1)
-	starOp := token.NewToken(token.STAR, "*", nil, 1)
-
-	expression := parser.NewBinaryExpr(
-		parser.NewUnaryExpr(minusOp, parser.NewLiteralExpr(123)),
-		starOp,
-		parser.NewGroupingExpr(parser.NewLiteralExpr(45.67)),
-	)
+const usage = `Usage:
+  glox [script [args...]]       run a script, or start the repl without one
+  glox -e 'code' [args...]      run code given on the command line
+  glox --vm script              run a script on the bytecode vm instead of the tree walker;
+                                it takes no script args and, of the limits, only -timeout
+  glox --trace script           run on the vm, printing the stack and each instruction to stderr
+  glox -O script [args...]      fold constants and drop dead code before running
+  glox -max-depth n script      fail with a stack overflow when calls nest deeper than n,
+                                at most 100000
+  glox -timeout 2s script       stop the script after a while; -max-steps and -max-allocs
+                                bound the statements it runs and the objects it creates
+  glox -caps time,env script    only give the script natives of these capabilities
+                                (time, io, fs, env, random, all or none; default all);
+                                without fs, imports stay in the script's directory and -path
+  glox -path dir:dir script     look for imported modules in these directories after the
+                                importing file's own; defaults to $GLOX_PATH
+  glox --strict script          fail instead of warning when the script uses a global it
+                                never defines
+  glox --tokens [--json] script print the tokens of a script instead of running it
+  glox run script [args...]     run a script
+  glox repl                     start the interactive repl
+  glox check [--strict] script  parse and resolve a script without running it; -path as for run
+  glox tokens [--json] script   print the tokens of a script as a table or JSON
+  glox ast script               print the syntax tree of a script
+  glox disasm script            print the bytecode the vm runs for a script
+  glox compile [-o out] script  save the bytecode of a script to a .loxc file for run to load
+  glox fmt [-w] script...       print scripts in canonical format, -w rewrites them
+  glox doc script               print the doc comments of a script as markdown`
 
-	astp := parser.AstPrinter{}
-	fmt.Println(astp.Print(expression))
+// the Go runtime's stack limit on 64-bit systems
+const defaultMaxStack = 1 << 30
 
-	scanner := scanner.NewScanner("22 + 2 / (2 * 8) + 10 /4")
-	scanner.ScanTokens()
+// runOptions are the flags that change how a script is run.
+type runOptions struct {
+	vm       bool
+	trace    bool
+	optimize bool
+	strict   bool
+	maxDepth int
+	timeout  time.Duration
+	limits   parser.Limits
+	caps     []parser.Capability
+
+	// script is the file the code was read from and path the directories
+	// imports are looked for in after the script's own
+	script string
+	path   []string
+}
 
-	// p := parser.NewParser(scanner.GetTokens())
-	// stmts := p.Parse()
+// compileError is a problem found before a program starts running.
+type compileError struct {
+	err error
+}
 
-	args := os.Args[1:]
+func (e compileError) Error() string {
+	return e.err.Error()
+}
 
-	lenArgs := len(args)
+func main() {
+	os.Exit(cli(os.Args[1:]))
+}
 
-	// fmt.Println(args)
+func cli(args []string) int {
+	if len(args) == 0 {
+		runPrompt(os.Stdin, os.Stdout)
+		return EX_OK
+	}
 
-	if lenArgs > 1 {
-		println("Usage: glox [script]")
-	} else if lenArgs == 1 {
-		runFile(args[0])
-	} else {
+	switch args[0] {
+	case "run":
+		return runCommand(args[1:])
+	case "repl":
 		runPrompt(os.Stdin, os.Stdout)
+		return EX_OK
+	case "check":
+		return checkCommand(args[1:])
+	case "tokens":
+		return tokensCommand(args[1:])
+	case "ast":
+		return fileCommand(args[1:], printAst)
+	case "compile":
+		return compileCommand(args[1:])
+	case "disasm":
+		return fileCommand(args[1:], disassemble)
+	case "doc":
+		return fileCommand(args[1:], printDoc)
+	case "fmt":
+		return fmtCommand(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+		return EX_OK
+	}
+
+	return runCommand(args)
+}
+
+func usageError(format string, a ...any) int {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	fmt.Fprintln(os.Stderr, usage)
+	return EX_USAGE
+}
+
+// exitCode reports err and returns the exit code matching the phase it
+// came from.
+func exitCode(err error) int {
+	if err == nil {
+		return EX_OK
+	}
+	fmt.Fprintln(os.Stderr, err)
+
+	var ce compileError
+	var pe *os.PathError
+	switch {
+	case errors.As(err, &ce):
+		return EX_DATAERR
+	case errors.As(err, &pe):
+		if errors.Is(err, os.ErrNotExist) {
+			return EX_NOINPUT
+		}
+		return EX_IOERR
 	}
+	return EX_SOFTWARE
 }
 
-func runFile(path string) error {
-	f, err := os.ReadFile(path)
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	code := flags.String("e", "", "run `code` instead of a script")
+	tokens := flags.Bool("tokens", false, "print the tokens of the script instead of running it")
+	asJSON := flags.Bool("json", false, "print the tokens as JSON")
+	var opts runOptions
+	flags.BoolVar(&opts.vm, "vm", false, "run on the bytecode vm")
+	flags.BoolVar(&opts.trace, "trace", false, "trace the execution of the bytecode vm")
+	flags.BoolVar(&opts.optimize, "O", false, "optimize the syntax tree before running it")
+	flags.BoolVar(&opts.strict, "strict", false, "fail on uses of globals that are never defined")
+	flags.IntVar(&opts.maxDepth, "max-depth", parser.DEFAULT_MAX_DEPTH, "how deep calls may nest")
+	flags.DurationVar(&opts.timeout, "timeout", 0, "stop the script after this long")
+	flags.IntVar(&opts.limits.MaxSteps, "max-steps", 0, "stop the script after this many steps")
+	flags.IntVar(&opts.limits.MaxAllocations, "max-allocs", 0, "stop the script after it creates this many objects")
+	caps := flags.String("caps", "all", "comma separated `capabilities` the script may use")
+	path := flags.String("path", os.Getenv("GLOX_PATH"), "`dirs` to import modules from, separated like PATH")
+	if err := flags.Parse(args); err != nil {
+		return usageError("")
+	}
+
+	var err error
+	if opts.caps, err = parser.ParseCapabilities(*caps); err != nil {
+		return usageError("glox: %v", err)
+	}
+	if opts.maxDepth < 1 || opts.maxDepth > parser.MAX_DEPTH {
+		return usageError("glox: -max-depth must be between 1 and %d", parser.MAX_DEPTH)
+	}
+
+	if *path != "" {
+		opts.path = filepath.SplitList(*path)
+	}
+
+	rest := flags.Args()
+	if *tokens {
+		return tokensCommand(append(jsonFlag(*asJSON), rest...))
+	}
+	if *code != "" {
+		if err := checkVM(flags, opts, rest); err != nil {
+			return usageError("glox: %v", err)
+		}
+		return exitCode(run(*code, rest, opts))
+	}
+	if len(rest) == 0 {
+		return usageError("glox run: missing script")
+	}
+
+	source, err := os.ReadFile(rest[0])
 	if err != nil {
-		return err
+		return exitCode(err)
+	}
+	if vm.IsBytecode(source) {
+		opts.vm = true
+	}
+	if err := checkVM(flags, opts, rest[1:]); err != nil {
+		return usageError("glox: %v", err)
+	}
+	opts.script = rest[0]
+	return exitCode(run(string(source), rest[1:], opts))
+}
+
+// vmUnsupported are the run flags the bytecode vm cannot honour.
+var vmUnsupported = []string{"max-steps", "max-allocs", "max-depth", "O", "caps", "strict"}
+
+// checkVM reports the flags and script arguments a run on the vm would
+// silently ignore.
+func checkVM(flags *flag.FlagSet, opts runOptions, args []string) error {
+	if !opts.vm && !opts.trace {
+		return nil
 	}
 
-	run(string(f))
+	var set []string
+	flags.Visit(func(f *flag.Flag) {
+		if slices.Contains(vmUnsupported, f.Name) && f.Value.String() != f.DefValue {
+			set = append(set, "-"+f.Name)
+		}
+	})
+	if len(set) > 0 {
+		return fmt.Errorf("the vm does not support %s", strings.Join(set, ", "))
+	}
+	if len(args) > 0 {
+		return errors.New("the vm does not support script arguments")
+	}
+	return nil
+}
+
+// fileCommand runs cmd on the source of the single script named in args.
+func fileCommand(args []string, cmd func(source string, out io.Writer) error) int {
+	if len(args) != 1 {
+		return usageError("expected exactly one script")
+	}
+
+	source, err := os.ReadFile(args[0])
+	if err != nil {
+		return exitCode(err)
+	}
+	return exitCode(cmd(string(source), os.Stdout))
+}
 
+func jsonFlag(asJSON bool) []string {
+	if asJSON {
+		return []string{"--json"}
+	}
 	return nil
 }
 
-func run(source string) {
-	// astp := parser.AstPrinter{}
-	scanner := scanner.NewScanner(source)
-	scanner.ScanTokens()
+func tokensCommand(args []string) int {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the tokens as JSON")
+	if err := flags.Parse(args); err != nil {
+		return usageError("")
+	}
 
-	p := parser.NewParser(scanner.GetTokens())
+	return fileCommand(flags.Args(), func(source string, out io.Writer) error {
+		tokens, err := scan(source)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return token.WriteJSON(out, tokens)
+		}
+		return token.WriteTable(out, tokens)
+	})
+}
 
-	// for i := 0; i < len(scanner.GetTokens()); i++ {
-	// 	fmt.Printf("line %d: '%s' %s\n", scanner.GetTokens()[i].Line, scanner.GetTokens()[i].Lexeme, scanner.GetTokens()[i].Type)
-	// }
+func checkCommand(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	strict := flags.Bool("strict", false, "fail on uses of globals that are never defined")
+	path := flags.String("path", os.Getenv("GLOX_PATH"), "`dirs` to import modules from, separated like PATH")
+	if err := flags.Parse(args); err != nil {
+		return usageError("")
+	}
 
-	stmts := p.Parse()
+	return fileCommand(flags.Args(), func(source string, out io.Writer) error {
+		_, stmts, err := parse(source)
+		if err != nil {
+			return err
+		}
+		// imports are read to learn the names they bring in
+		options := []parser.Option{parser.WithScriptPath(flags.Arg(0))}
+		if *path != "" {
+			options = append(options, parser.WithModulePath(filepath.SplitList(*path)...))
+		}
+		return resolve(parser.NewInterpreter(stmts, options...), stmts, *strict)
+	})
+}
 
-	i := parser.NewInterpreter(stmts)
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result back to the script")
+	if err := flags.Parse(args); err != nil {
+		return usageError("")
+	}
+	if flags.NArg() == 0 {
+		return usageError("glox fmt: missing script")
+	}
+
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return exitCode(err)
+		}
+
+		tokens, stmts, err := parse(string(source))
+		if err != nil {
+			return exitCode(err)
+		}
+		formatted := parser.Format(tokens, stmts)
+
+		if *write {
+			if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+				return exitCode(err)
+			}
+		} else {
+			fmt.Print(formatted)
+		}
+	}
+	return EX_OK
+}
+
+func compileCommand(args []string) int {
+	flags := flag.NewFlagSet("compile", flag.ContinueOnError)
+	output := flags.String("o", "", "write the bytecode to `file` instead of the script name with .loxc")
+	if err := flags.Parse(args); err != nil {
+		return usageError("")
+	}
+	if flags.NArg() != 1 {
+		return usageError("glox compile: expected exactly one script")
+	}
+
+	path := flags.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(path, filepath.Ext(path)) + ".loxc"
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return exitCode(err)
+	}
+	function, err := compile(string(source))
+	if err != nil {
+		return exitCode(err)
+	}
+
+	var b bytes.Buffer
+	if err := vm.WriteBytecode(&b, function); err != nil {
+		return exitCode(err)
+	}
+	return exitCode(os.WriteFile(*output, b.Bytes(), 0644))
+}
+
+// catch turns a panic raised by the scanner, parser or resolver into a
+// compileError stored in err.
+func catch(err *error) {
+	if r := recover(); r != nil {
+		*err = compileError{fmt.Errorf("%v", r)}
+	}
+}
+
+func scan(source string) (tokens []token.Token, err error) {
+	s := scanner.NewScanner(source)
+	s.ScanTokens()
+	if s.HadError() {
+		return nil, compileError{errors.New("could not scan the script")}
+	}
+	return s.GetTokens(), nil
+}
+
+func parse(source string) (tokens []token.Token, stmts []parser.Stmt, err error) {
+	defer catch(&err)
+
+	tokens, err = scan(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, parser.NewParser(tokens).Parse(), nil
+}
+
+// resolve resolves stmts, printing the resolver's warnings to stderr or,
+// when strict, failing with them.
+func resolve(i *parser.Interpreter, stmts []parser.Stmt, strict bool) (err error) {
+	defer catch(&err)
 
 	r := parser.NewResolver(i)
 	r.ResolveStmts(stmts)
 
-	i.Interpret(stmts)
+	warnings := r.Warnings()
+	if strict && len(warnings) > 0 {
+		return compileError{errors.New(strings.Join(warnings, "\n"))}
+	}
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "warning: "+w)
+	}
+	return nil
+}
+
+func interpret(i *parser.Interpreter, stmts []parser.Stmt) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
 
+	i.Interpret(stmts)
+	return nil
 }
 
-func runPrompt(in io.Reader, out io.Writer) {
-	PROMPT := "->"
-	s := bufio.NewScanner(in)
+func run(source string, args []string, opts runOptions) error {
+	if opts.vm || opts.trace {
+		return runVM(source, opts)
+	}
+
+	_, stmts, err := parse(source)
+	if err != nil {
+		return err
+	}
 
-	for {
-		fmt.Fprint(out, PROMPT)
-		scanned := s.Scan()
-		if !scanned {
-			break
+	// leave room on the Go stack for the deepest calls -max-depth allows
+	if stack := opts.maxDepth * parser.STACK_PER_CALL; stack > defaultMaxStack {
+		debug.SetMaxStack(stack)
+	}
+
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	options := []parser.Option{
+		parser.WithArgs(args),
+		parser.WithMaxDepth(opts.maxDepth),
+		parser.WithContext(ctx),
+		parser.WithLimits(opts.limits),
+		parser.WithCapabilities(opts.caps...),
+		parser.WithModulePath(opts.path...),
+	}
+	if opts.script != "" {
+		options = append(options, parser.WithScriptPath(opts.script))
+	}
+
+	i := parser.NewInterpreter(stmts, options...)
+	if err := resolve(i, stmts, opts.strict); err != nil {
+		return err
+	}
+	if opts.optimize {
+		stmts = parser.Optimize(stmts)
+	}
+	return interpret(i, stmts)
+}
+
+// compile returns the bytecode for source, which is either Lox code or the
+// contents of a .loxc file.
+func compile(source string) (*vm.Function, error) {
+	if vm.IsBytecode([]byte(source)) {
+		function, err := vm.ReadBytecode(strings.NewReader(source))
+		if err != nil {
+			return nil, compileError{err}
 		}
+		return function, nil
+	}
+
+	tokens, err := scan(source)
+	if err != nil {
+		return nil, err
+	}
+
+	function, err := vm.Compile(tokens)
+	if err != nil {
+		return nil, compileError{err}
+	}
+	return function, nil
+}
+
+// runVM compiles source to bytecode and runs it on the vm.
+func runVM(source string, opts runOptions) error {
+	function, err := compile(source)
+	if err != nil {
+		return err
+	}
+
+	machine := vm.New()
+	if opts.trace {
+		machine.SetTrace(os.Stderr)
+	}
+	if opts.timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+		defer cancel()
+		machine.SetContext(ctx)
+	}
+	return machine.Interpret(function)
+}
 
-		run(s.Text())
+func disassemble(source string, out io.Writer) error {
+	function, err := compile(source)
+	if err != nil {
+		return err
+	}
+
+	vm.Disassemble(out, function)
+	return nil
+}
+
+func printAst(source string, out io.Writer) error {
+	_, stmts, err := parse(source)
+	if err != nil {
+		return err
 	}
+
+	astp := parser.AstPrinter{}
+	fmt.Fprint(out, astp.PrintStmts(stmts))
+	return nil
+}
+
+func printDoc(source string, out io.Writer) error {
+	_, stmts, err := parse(source)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(out, parser.Docs(stmts))
+	return nil
 }
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DEFAULT_MAX_DEPTH is how many calls may be active at once before a
+// script fails with a stack overflow, unless WithMaxDepth says otherwise.
+const DEFAULT_MAX_DEPTH = 10000
+
+// MAX_DEPTH is the deepest WithMaxDepth allows. Every Lox call takes a few
+// kilobytes of Go stack, more when it is nested in blocks and expressions,
+// so deeper calls would crash the Go runtime before the script sees a
+// stack overflow. STACK_PER_CALL is the Go stack a host should allow for
+// each call, see debug.SetMaxStack.
+const (
+	MAX_DEPTH      = 100000
+	STACK_PER_CALL = 16 << 10
+)
+
+// frames shown at each end of a long stack trace
+const traceEnds = 10
+
+// frame is a function call in progress; line is where the function called
+// the next one up the stack.
+type frame struct {
+	name string
+	line int
+}
+
+// RuntimeError is a failure of a running script with the Lox call stack at
+// the moment it happened, innermost call first.
+type RuntimeError struct {
+	Message string
+	// Line is where the error happened
+	Line  int
+	Trace []string
+	// Err is the error that stopped the script, if it was raised as one
+	Err error
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RuntimeError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Message)
+
+	for n, line := range e.Trace {
+		if len(e.Trace) > 2*traceEnds && n == traceEnds {
+			fmt.Fprintf(&sb, "\n... %d more calls", len(e.Trace)-2*traceEnds)
+		}
+		if len(e.Trace) > 2*traceEnds && n >= traceEnds && n < len(e.Trace)-traceEnds {
+			continue
+		}
+		sb.WriteString("\n" + line)
+	}
+	return sb.String()
+}
+
+// WithMaxDepth sets how deep calls may nest before a stack overflow, at
+// most MAX_DEPTH.
+func WithMaxDepth(depth int) Option {
+	return func(i *Interpreter) {
+		i.maxDepth = min(depth, MAX_DEPTH)
+	}
+}
+
+// runtimeError wraps a panic value raised while running a script in a
+// RuntimeError with the current call stack. Values that already are one
+// are returned as they are.
+func (i *Interpreter) runtimeError(value any) *RuntimeError {
+	if err, ok := value.(*RuntimeError); ok {
+		return err
+	}
+
+	trace := []string{}
+	line := i.line
+	for n := len(i.frames) - 1; n >= 0; n-- {
+		name := i.frames[n].name
+		if n == 0 {
+			name = "script"
+		}
+		trace = append(trace, fmt.Sprintf("[line %d] in %s", line, name))
+		if n > 0 {
+			line = i.frames[n-1].line
+		}
+	}
+
+	err, _ := value.(error)
+	return &RuntimeError{Message: fmt.Sprintf("%v", value), Line: i.line, Trace: trace, Err: err}
+}
+
+// call runs callee with a frame for it on the call stack, turning any
+// failure inside it into a RuntimeError that records the stack.
+func (i *Interpreter) call(callee LoxCallable, arguments []any, line int) (value any) {
+	if len(i.frames) > i.maxDepth {
+		panic(i.runtimeError("Stack overflow."))
+	}
+
+	i.frames[len(i.frames)-1].line = line
+	i.frames = append(i.frames, frame{name: callableName(callee)})
+	i.line = line
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(Return); !ok {
+				r = i.runtimeError(r)
+			}
+			i.popFrame()
+			panic(r)
+		}
+		i.popFrame()
+	}()
+
+	return callee.Call(i, arguments)
+}
+
+func (i *Interpreter) popFrame() {
+	i.frames = i.frames[:len(i.frames)-1]
+	i.line = i.frames[len(i.frames)-1].line
+}
+
+func callableName(callee LoxCallable) string {
+	switch c := callee.(type) {
+	case *Function:
+		return c.declaration.name.Lexeme
+	case *Class:
+		return c.name
+	case *native:
+		return c.name
+	case *help:
+		return "help"
+	}
+	return fmt.Sprintf("%v", callee)
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestRuntimeErrorTrace(t *testing.T) {
+	source := `fun inner(x) {
+  return x + nil;
+}
+
+class A {
+  method() {
+    return inner(1);
+  }
+}
+
+print "before";
+A().method();
+`
+	expected := `Operands of '+' must be two numbers or two strings.
+[line 2] in inner
+[line 7] in method
+[line 12] in script`
+
+	out, err := run(source, false)
+	if out != "before\n" {
+		t.Fatalf("output wrong, got %q", out)
+	}
+	if err == nil || err.Error() != expected {
+		t.Fatalf("error wrong, expected:\n%s\ngot:\n%v", expected, err)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	stmts := parse("fun f(n) { return f(n + 1); }\nf(0);")
+	i := NewInterpreter(stmts, WithOutput(io.Discard), WithMaxDepth(50))
+	NewResolver(i).ResolveStmts(stmts)
+
+	defer func() {
+		var re *RuntimeError
+		err, _ := recover().(error)
+		if !errors.As(err, &re) {
+			t.Fatalf("expected a RuntimeError, got %v", err)
+		}
+		if re.Message != "Stack overflow." || len(re.Trace) != 51 {
+			t.Fatalf("expected a stack overflow 51 frames deep, got %q with %d frames", re.Message, len(re.Trace))
+		}
+		if re.Trace[50] != "[line 2] in script" {
+			t.Fatalf("outermost frame wrong, got %q", re.Trace[50])
+		}
+	}()
+	i.Interpret(stmts)
+}
+
+func TestMaxDepthIsCapped(t *testing.T) {
+	i := NewInterpreter(nil, WithMaxDepth(2000000))
+	if i.maxDepth != MAX_DEPTH {
+		t.Fatalf("expected the depth to be capped at %d, got %d", MAX_DEPTH, i.maxDepth)
+	}
+}
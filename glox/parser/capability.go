@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Capability names a group of natives that reach outside the interpreter.
+// Natives that only compute on their arguments need no capability and are
+// always defined.
+type Capability string
+
+const (
+	CAP_TIME   Capability = "time"
+	CAP_IO     Capability = "io"
+	CAP_FS     Capability = "fs"
+	CAP_ENV    Capability = "env"
+	CAP_RANDOM Capability = "random"
+)
+
+var ALL_CAPABILITIES = []Capability{CAP_TIME, CAP_IO, CAP_FS, CAP_ENV, CAP_RANDOM}
+
+// natives holds the natives of every capability and coreNatives those that
+// need none, registered by the files that implement them.
+var (
+	natives     = map[Capability][]*native{}
+	coreNatives = []*native{}
+	constants   = map[string]any{}
+)
+
+func register(capability Capability, n *native) {
+	natives[capability] = append(natives[capability], n)
+}
+
+func registerCore(n *native) {
+	coreNatives = append(coreNatives, n)
+}
+
+// registerConstant defines a global value such as PI in every interpreter.
+func registerConstant(name string, value any) {
+	constants[name] = value
+}
+
+func init() {
+	register(CAP_TIME, &native{
+		name:   "clock",
+		params: 0,
+		doc:    "Returns the time in milliseconds.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			return float64(time.Now().UnixMilli())
+		},
+	})
+
+	register(CAP_ENV, &native{
+		name:   "getenv",
+		params: 1,
+		doc:    "Returns the value of an environment variable, or nil when it is not set.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			name, ok := arguments[0].(string)
+			if !ok {
+				panic("getenv expects the name of a variable.")
+			}
+			if value, ok := os.LookupEnv(name); ok {
+				return value
+			}
+			return nil
+		},
+	})
+}
+
+// WithCapabilities defines only the natives of the given capabilities;
+// without this option an interpreter gets all of them. Pass none for a
+// sandbox where scripts can only compute.
+func WithCapabilities(capabilities ...Capability) Option {
+	return func(i *Interpreter) {
+		i.capabilities = capabilities
+	}
+}
+
+// ParseCapabilities reads a comma separated list of capabilities; "all"
+// stands for every one and "none" or "" for none.
+func ParseCapabilities(list string) ([]Capability, error) {
+	switch list {
+	case "all":
+		return ALL_CAPABILITIES, nil
+	case "", "none":
+		return []Capability{}, nil
+	}
+
+	capabilities := []Capability{}
+	for _, name := range strings.Split(list, ",") {
+		c := Capability(strings.TrimSpace(name))
+		if !knownCapability(c) {
+			return nil, fmt.Errorf("unknown capability %q", name)
+		}
+		capabilities = append(capabilities, c)
+	}
+	return capabilities, nil
+}
+
+func knownCapability(c Capability) bool {
+	for _, known := range ALL_CAPABILITIES {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCapability reports whether the natives of c are defined.
+func (i *Interpreter) HasCapability(c Capability) bool {
+	for _, enabled := range i.capabilities {
+		if c == enabled {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Interpreter) defineNatives() {
+	i.globals.define("Error", i.errorClass)
+	for name, value := range constants {
+		i.globals.define(name, value)
+	}
+	for _, n := range coreNatives {
+		i.globals.define(n.name, n)
+	}
+	for _, c := range i.capabilities {
+		for _, n := range natives[c] {
+			i.globals.define(n.name, n)
+		}
+	}
+}
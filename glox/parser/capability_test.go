@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	sandbox := NewInterpreter(nil, WithCapabilities())
+	for _, name := range []string{"clock", "getenv"} {
+		if _, ok := sandbox.Globals()[name]; ok {
+			t.Fatalf("sandbox - %s should not be defined", name)
+		}
+	}
+	if _, ok := sandbox.Globals()["help"]; !ok {
+		t.Fatal("sandbox - help should always be defined")
+	}
+
+	timeOnly := NewInterpreter(nil, WithCapabilities(CAP_TIME))
+	if _, ok := timeOnly.Globals()["clock"]; !ok {
+		t.Fatal("time - clock should be defined")
+	}
+	if _, ok := timeOnly.Globals()["getenv"]; ok {
+		t.Fatal("time - getenv should not be defined")
+	}
+
+	everything := NewInterpreter(nil)
+	for _, c := range ALL_CAPABILITIES {
+		if !everything.HasCapability(c) {
+			t.Fatalf("default - expected capability %s", c)
+		}
+	}
+}
+
+func TestParseCapabilities(t *testing.T) {
+	tests := []struct {
+		list     string
+		expected int
+		ok       bool
+	}{
+		{"all", len(ALL_CAPABILITIES), true},
+		{"none", 0, true},
+		{"time, env", 2, true},
+		{"time,network", 0, false},
+	}
+
+	for _, tt := range tests {
+		capabilities, err := ParseCapabilities(tt.list)
+		if (err == nil) != tt.ok || len(capabilities) != tt.expected {
+			t.Fatalf("%q - expected %d capabilities, got %v, %v", tt.list, tt.expected, capabilities, err)
+		}
+	}
+}
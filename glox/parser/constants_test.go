@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstantErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{`const x = 1; x = 2;`, "cannot assign to constant 'x'."},
+		{`{ const x = 1; fun f() { x = 2; } }`, "cannot assign to constant 'x'."},
+		{`const x = 1; var x = 2;`, "cannot redeclare constant 'x'."},
+		{`{ const x = 1; var x = 2; }`, "cannot redeclare constant 'x'."},
+		{`const x;`, "Expect '=' after constant name."},
+		{`class P {} var p = freeze(P()); p.x = 1;`, "cannot set 'x' on a frozen P instance."},
+		{`class P { set() { this.x = 1; } } freeze(P()).set();`, "cannot set 'x' on a frozen P instance."},
+		{`freeze(1);`, "freeze: argument 1 must be an instance."},
+	}
+
+	for _, tt := range tests {
+		_, err := run(tt.source, false)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Fatalf("%s - expected error %q, got %v", tt.source, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCore(&native{name: "num", params: 1, doc: "Returns a number or the number a string spells, or nil when it spells none.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			switch v := arguments[0].(type) {
+			case float64:
+				return v
+			case string:
+				f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil {
+					return nil
+				}
+				return f
+			}
+			panic(fmt.Sprintf("num: cannot convert %s to a number.", typeName(arguments[0])))
+		}})
+
+	registerCore(&native{name: "str", params: 1, doc: "Returns a value as print would show it.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			interpreter.allocate()
+			return interpreter.Stringify(arguments[0])
+		}})
+
+	registerCore(&native{name: "bool", params: 1, doc: "Returns false for nil and false, true for anything else.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			return isTruthy(arguments[0])
+		}})
+
+	registerCore(&native{name: "type", params: 1, doc: "Returns the kind of a value: number, string, bool, nil, function, class, trait, instance or list.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			return typeName(arguments[0])
+		}})
+
+	registerCore(&native{name: "isInstance", params: 2, doc: "Returns whether a value is an instance of a class or of one of its subclasses, or of a class with a trait.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			instance, ok := arguments[0].(*LoxInstance)
+			switch c := arguments[1].(type) {
+			case *Class:
+				return ok && instance.isSubclassOf(c)
+			case *Trait:
+				return ok && instance.hasTrait(c)
+			}
+			panic("isInstance: argument 2 must be a class or a trait.")
+		}})
+
+	registerCore(&native{name: "fields", params: 1, doc: "Returns the names of the public fields of an instance, sorted.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			instance, ok := arguments[0].(*LoxInstance)
+			if !ok {
+				panic("fields: argument 1 must be an instance.")
+			}
+			names := make([]string, 0, len(instance.fields))
+			for name := range instance.fields {
+				if !isPrivate(name) {
+					names = append(names, name)
+				}
+			}
+			return nameList(interpreter, names)
+		}})
+
+	registerCore(&native{name: "freeze", params: 1, doc: "Makes the fields of an instance read-only and returns it.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			instance, ok := arguments[0].(*LoxInstance)
+			if !ok {
+				panic("freeze: argument 1 must be an instance.")
+			}
+			instance.frozen = true
+			return instance
+		}})
+
+	registerCore(&native{name: "methods", params: 1, doc: "Returns the names of the public methods of a class, inherited ones included, sorted.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			class, ok := arguments[0].(*Class)
+			if !ok {
+				panic("methods: argument 1 must be a class.")
+			}
+			seen := map[string]bool{}
+			names := []string{}
+			for c := class; c != nil; c = c.superclass {
+				for name := range c.methods {
+					if !seen[name] && !isPrivate(name) {
+						seen[name] = true
+						names = append(names, name)
+					}
+				}
+			}
+			return nameList(interpreter, names)
+		}})
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case *Class:
+		return "class"
+	case *Trait:
+		return "trait"
+	case *LoxInstance:
+		return "instance"
+	case *List:
+		return "list"
+	case LoxCallable:
+		return "function"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// nameList returns names sorted as a Lox list of strings.
+func nameList(interpreter *Interpreter, names []string) *List {
+	sort.Strings(names)
+	elements := make([]any, len(names))
+	for i, name := range names {
+		elements[i] = name
+	}
+	interpreter.allocate()
+	return NewList(elements)
+}
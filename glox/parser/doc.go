@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"strings"
+)
+
+func signature(f *FunctionStmt) string {
+	if f.getter {
+		return f.name.Lexeme
+	}
+
+	params := make([]string, len(f.params))
+	for i, p := range f.params {
+		params[i] = p.Lexeme
+	}
+	name := f.name.Lexeme
+	switch {
+	case f.static:
+		name = "static " + name
+	case f.setter:
+		name += "="
+	}
+	return name + "(" + strings.Join(params, ", ") + ")"
+}
+
+func classHeader(name string, superclass string, traits []string) string {
+	header := "class " + name
+	if superclass != "" {
+		header += " < " + superclass
+	}
+	return header + withTraits(traits)
+}
+
+func withTraits(traits []string) string {
+	if len(traits) == 0 {
+		return ""
+	}
+	return " with " + strings.Join(traits, ", ")
+}
+
+func traitNames(traits []*Variable) []string {
+	names := make([]string, len(traits))
+	for i, t := range traits {
+		names[i] = t.name.Lexeme
+	}
+	return names
+}
+
+// Docs renders the doc comments of the top level functions and classes in
+// stmts, and of the methods of those classes, as markdown.
+func Docs(stmts []Stmt) string {
+	var sb strings.Builder
+
+	for _, s := range stmts {
+		if e, ok := s.(*ExportStmt); ok {
+			s = e.declaration
+		}
+		switch d := s.(type) {
+		case *FunctionStmt:
+			sb.WriteString("## fun " + signature(d) + "\n\n")
+			writeDoc(&sb, d.doc)
+
+		case *ClassStmt:
+			superclass := ""
+			if d.superclass != nil {
+				superclass = d.superclass.name.Lexeme
+			}
+			sb.WriteString("## " + classHeader(d.name.Lexeme, superclass, traitNames(d.traits)) + "\n\n")
+			writeDoc(&sb, d.doc)
+			writeMethodDocs(&sb, d.methods)
+
+		case *TraitStmt:
+			sb.WriteString("## trait " + d.name.Lexeme + withTraits(traitNames(d.traits)) + "\n\n")
+			writeDoc(&sb, d.doc)
+			writeMethodDocs(&sb, d.methods)
+		}
+	}
+
+	return sb.String()
+}
+
+func writeMethodDocs(sb *strings.Builder, methods []*FunctionStmt) {
+	for _, m := range methods {
+		sb.WriteString("### " + signature(m) + "\n\n")
+		writeDoc(sb, m.doc)
+	}
+}
+
+func writeDoc(sb *strings.Builder, doc string) {
+	if doc == "" {
+		return
+	}
+	sb.WriteString(doc + "\n\n")
+}
@@ -0,0 +1,111 @@
+package parser
+
+import "testing"
+
+func TestDocComments(t *testing.T) {
+	source := `/// Adds two numbers.
+/// Both must be numbers.
+fun add(a, b) { return a + b; }
+
+/// Not about anything, a blank line follows.
+
+fun undocumented() {}
+
+// An ordinary comment.
+fun plain() {}
+
+/// Stays with the count.
+/// Ended by a blank line.
+
+/// Counts things.
+class Counter {
+  /// Starts at zero.
+  init() { this.n = 0; }
+
+  /// Adds one.
+  add() { this.n = this.n + 1; }
+  get() { return this.n; }
+}
+
+/// Can be exported.
+export fun shared() {}
+`
+	expected := `## fun add(a, b)
+
+Adds two numbers.
+Both must be numbers.
+
+## fun undocumented()
+
+## fun plain()
+
+## class Counter
+
+Counts things.
+
+### init()
+
+Starts at zero.
+
+### add()
+
+Adds one.
+
+### get()
+
+## fun shared()
+
+Can be exported.
+
+`
+	if got := Docs(parse(source)); got != expected {
+		t.Fatalf("docs wrong, expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"function", `
+/// Adds two numbers.
+/// Both must be numbers.
+fun add(a, b) { return a + b; }
+help(add);`, "fun add(a, b)\n    Adds two numbers.\n    Both must be numbers.\n"},
+		{"class and methods", `
+/// Counts things.
+class Counter {
+  /// Adds one.
+  add() {}
+  /// Starts at zero.
+  init() {}
+  get() {}
+}
+help(Counter);`, "class Counter\n    Counts things.\n    add()\n        Adds one.\n    get()\n    init()\n        Starts at zero.\n"},
+		{"bound method", `
+class A {
+  /// Says hi.
+  hi() {}
+}
+help(A().hi);`, "fun hi()\n    Says hi.\n"},
+		{"blank line", `
+/// Not f's.
+
+fun f() {}
+help(f);`, "fun f()\n"},
+		{"native", `help(len);`, "<native fn 'len'>\n    Returns the number of characters in a string or elements in a list.\n"},
+		{"value", `help(1);`, "no documentation for 1\n"},
+	}
+
+	for _, tt := range tests {
+		out, err := run(tt.source, false)
+		if err != nil {
+			t.Fatalf("%s - %v", tt.name, err)
+		}
+		if out != tt.expected {
+			t.Fatalf("%s - expected:\n%q\ngot:\n%q", tt.name, tt.expected, out)
+		}
+	}
+}
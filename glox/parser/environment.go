@@ -7,6 +7,8 @@ import (
 type Environment struct {
 	values    map[string]any
 	enclosing *Environment
+	// names declared with const
+	constants map[string]bool
 }
 
 func NewEnvironment(enclosing *Environment) *Environment {
@@ -26,17 +28,29 @@ func (e *Environment) Get(name *token.Token) any {
 	if e.enclosing != nil {
 		return e.enclosing.Get(name)
 	}
-	panic("boom")
+	panic("undefined variable '" + name.Lexeme + "'.")
 }
 
 func (e *Environment) define(name string, value any) {
+	if e.constants[name] {
+		panic("cannot redeclare constant '" + name + "'.")
+	}
 	e.values[name] = value
 }
 
+// defineConstant defines name as a value that cannot be assigned to.
+func (e *Environment) defineConstant(name string, value any) {
+	e.define(name, value)
+	if e.constants == nil {
+		e.constants = map[string]bool{}
+	}
+	e.constants[name] = true
+}
+
 func (e *Environment) Assign(name *token.Token, value any) {
 	_, ok := e.values[name.Lexeme]
 	if ok {
-		e.define(name.Lexeme, value)
+		e.assign(name.Lexeme, value)
 		return
 	}
 	if e.enclosing != nil {
@@ -49,5 +63,20 @@ func (e *Environment) Assign(name *token.Token, value any) {
 }
 
 func (e *Environment) AssignAt(distance int, name *token.Token, value any) {
-	e.ancestor(distance).values[name.Lexeme] = value
+	e.ancestor(distance).assign(name.Lexeme, value)
+}
+
+func (e *Environment) assign(name string, value any) {
+	if e.constants[name] {
+		panic("cannot assign to constant '" + name + "'.")
+	}
+	e.values[name] = value
+}
+
+// root returns the outermost environment, the globals of a module.
+func (e *Environment) root() *Environment {
+	for e.enclosing != nil {
+		e = e.enclosing
+	}
+	return e
 }
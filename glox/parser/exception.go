@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"errors"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/scanner"
+)
+
+// errorClassSource declares the built-in Error class. It is written in Lox
+// so that scripts can subclass it like any other class.
+const errorClassSource = `
+/// An error raised by throw or by the interpreter. message says what went
+/// wrong and line where; a thrown Error without a line gets the line of
+/// its throw.
+class Error {
+  init(message) {
+    this.message = message;
+    this.line = nil;
+  }
+}
+`
+
+// Thrown carries a value raised by a throw statement to the nearest catch.
+// When nothing catches it the script stops with it as the error.
+type Thrown struct {
+	Value any
+}
+
+func (t *Thrown) Error() string {
+	if instance, ok := t.Value.(*LoxInstance); ok {
+		if message, ok := instance.fields["message"]; ok {
+			return instance.name + ": " + stringify(message)
+		}
+	}
+	return "uncaught " + stringify(t.Value)
+}
+
+// defineErrorClass creates the Error class of the interpreter; every
+// module shares it so errors from one can be recognised in another.
+func (i *Interpreter) defineErrorClass() {
+	s := scanner.NewScanner(errorClassSource)
+	s.ScanTokens()
+	stmts := NewParser(s.GetTokens()).Parse()
+	NewResolver(i).ResolveStmts(stmts)
+
+	env := NewEnvironment(nil)
+	environment := i.environment
+	i.environment = env
+	stmts[0].Accept(i)
+	i.environment = environment
+
+	i.errorClass = env.values["Error"].(*Class)
+}
+
+// newError returns an instance of Error for a failure at line.
+func (i *Interpreter) newError(message string, line int) *LoxInstance {
+	e := NewLoxInstance(i.errorClass)
+	e.fields["message"] = message
+	e.fields["line"] = float64(line)
+	return e
+}
+
+// caught returns the Lox value a catch clause sees for the panic value r.
+// Returns and exceeded limits cannot be caught.
+func (i *Interpreter) caught(r any) (value any, ok bool) {
+	if _, ok := r.(Return); ok {
+		return nil, false
+	}
+
+	if err, ok := r.(error); ok {
+		var limit *LimitExceeded
+		if errors.As(err, &limit) {
+			return nil, false
+		}
+		var thrown *Thrown
+		if errors.As(err, &thrown) {
+			return thrown.Value, true
+		}
+	}
+
+	err := i.runtimeError(r)
+	return i.newError(err.Message, err.Line), true
+}
+
+// tryBlock runs block and recovers whatever it throws.
+func (i *Interpreter) tryBlock(block *BlockStmt) (value any, threw bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if value, threw = i.caught(r); !threw {
+				panic(r)
+			}
+		}
+	}()
+
+	i.executeBlock(block.statments, NewEnvironment(i.environment))
+	return nil, false
+}
+
+func (i *Interpreter) visitTryStmt(s *TryStmt) any {
+	if s.finallyBody != nil {
+		defer i.executeBlock(s.finallyBody.statments, NewEnvironment(i.environment))
+	}
+
+	if s.catchBody == nil {
+		i.executeBlock(s.body.statments, NewEnvironment(i.environment))
+		return nil
+	}
+
+	if value, threw := i.tryBlock(s.body); threw {
+		env := NewEnvironment(i.environment)
+		env.define(s.catchName.Lexeme, value)
+		i.executeBlock(s.catchBody.statments, env)
+	}
+	return nil
+}
+
+func (i *Interpreter) visitThrowStmt(s *ThrowStmt) any {
+	value := s.value.Accept(i)
+	i.line = s.keyword.Line
+
+	if instance, ok := value.(*LoxInstance); ok && instance.isSubclassOf(i.errorClass) {
+		if line, ok := instance.fields["line"]; !ok || line == nil {
+			instance.fields["line"] = float64(s.keyword.Line)
+		}
+	}
+	panic(&Thrown{Value: value})
+}
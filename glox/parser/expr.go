@@ -37,6 +37,8 @@ type ExprVisitor interface {
 	VisitSet(expr *Set) any
 	VisitThis(expr *This) any
 	VisitSuper(expr *Super) any
+	VisitIndex(expr *Index) any
+	VisitIndexSet(expr *IndexSet) any
 }
 
 type Binary struct {
@@ -94,6 +96,9 @@ func (g *Grouping) Accept(visitor ExprVisitor) any {
 
 type Literal struct {
 	Value any
+	// allocations counts the string concatenations the optimizer folded
+	// into the literal; they are still charged each time it is evaluated
+	allocations int
 	defaultStartEnd
 }
 
@@ -226,3 +231,28 @@ func NewSuper(keyword *token.Token, method *token.Token) *Super {
 func (s *Super) Accept(visitor ExprVisitor) any {
 	return visitor.VisitSuper(s)
 }
+
+// Index reads object[index].
+type Index struct {
+	object  Expr
+	bracket *token.Token
+	index   Expr
+	defaultStartEnd
+}
+
+func (i *Index) Accept(visitor ExprVisitor) any {
+	return visitor.VisitIndex(i)
+}
+
+// IndexSet assigns object[index] = value.
+type IndexSet struct {
+	object  Expr
+	bracket *token.Token
+	index   Expr
+	value   Expr
+	defaultStartEnd
+}
+
+func (i *IndexSet) Accept(visitor ExprVisitor) any {
+	return visitor.VisitIndexSet(i)
+}
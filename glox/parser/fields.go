@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// isPrivate reports whether name is a private "#name", which can only be
+// reached through this in the methods of a class.
+func isPrivate(name string) bool {
+	return strings.HasPrefix(name, "#")
+}
+
+// privateNames are the private fields and methods a class or trait
+// declares, the only ones its methods may use.
+type privateNames struct {
+	owner string
+	names map[string]bool
+}
+
+func newPrivateNames(owner string, fields []*VarStmt, methods []*FunctionStmt) *privateNames {
+	p := &privateNames{owner: owner, names: map[string]bool{}}
+	for _, f := range fields {
+		if isPrivate(f.name.Lexeme) {
+			p.names[f.name.Lexeme] = true
+		}
+	}
+	for _, m := range methods {
+		if isPrivate(m.name.Lexeme) {
+			p.names[m.name.Lexeme] = true
+		}
+	}
+	return p
+}
+
+// hasPrivate reports whether c or one of its superclasses has the private
+// field or method name.
+func (c *Class) hasPrivate(name string) bool {
+	for ; c != nil; c = c.superclass {
+		for _, f := range c.fields {
+			if f.name.Lexeme == name {
+				return true
+			}
+		}
+		for _, table := range c.tables() {
+			if _, ok := table[name]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkPrivates makes sure c does not reuse a private name of a
+// superclass, which would let the two classes reach each other's field or
+// method.
+func (c *Class) checkPrivates() {
+	if c.superclass == nil {
+		return
+	}
+
+	names := []string{}
+	for _, f := range c.fields {
+		names = append(names, f.name.Lexeme)
+	}
+	for _, table := range c.tables() {
+		for name := range table {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if isPrivate(name) && c.superclass.hasPrivate(name) {
+			panic(fmt.Sprintf("%s cannot declare '%s'; a superclass already has a private member by that name.", c.name, name))
+		}
+	}
+}
+
+// declares reports whether c or one of its superclasses declares the
+// field name.
+func (c *Class) declares(name string) bool {
+	for ; c != nil; c = c.superclass {
+		for _, f := range c.fields {
+			if f.name.Lexeme == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// initFields sets the fields declared by c and its superclasses on a new
+// instance, those of the superclasses first. Initializers run with this
+// bound to the instance.
+func (i *Interpreter) initFields(c *Class, instance *LoxInstance) {
+	if c.superclass != nil {
+		i.initFields(c.superclass, instance)
+	}
+	if len(c.fields) == 0 {
+		return
+	}
+
+	environment, globals := i.environment, i.globals
+	defer func() {
+		i.environment, i.globals = environment, globals
+	}()
+	i.environment = NewEnvironment(c.fieldScope)
+	i.environment.define("this", instance)
+	i.globals = c.fieldScope.root()
+
+	for _, f := range c.fields {
+		var value any
+		if f.initializer != nil {
+			value = f.initializer.Accept(i)
+		}
+		instance.fields[f.name.Lexeme] = value
+	}
+}
+
+// undeclared reports the use of a field a strict class does not declare.
+func (li *LoxInstance) undeclared(name string) {
+	panic(fmt.Sprintf("%s has no field '%s'; strict classes only have the fields they declare.", li.name, name))
+}
@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrivateFieldErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{`class A { var #x = 1; } print A().#x;`, "cannot use private property '#x' outside of a class."},
+		{`class A { var #x = 1; same(other) { return other.#x; } }`, "private property '#x' can only be used through 'this'."},
+		{`var #x = 1;`, "'#x' is private; only fields and methods can have private names."},
+		{`fun f(#x) {}`, "'#x' is private; only fields and methods can have private names."},
+		{`class A { var x; var x; }`, "field 'x' is declared twice in A."},
+		{`class A { var #x = 1; } class B < A { peek() { return this.#x; } }`, "private property '#x' is not declared in B."},
+		{`class A { set() { this.#y = 2; } }`, "private property '#y' is not declared in A."},
+		{`class A { #hidden() {} } class B < A { call() { this.#hidden(); } }`, "private property '#hidden' is not declared in B."},
+		{`class A { var #x = 1; } class B < A { var #x = 2; }`, "B cannot declare '#x'; a superclass already has a private member by that name."},
+		{`class A { #m() {} } class B < A { #m() {} }`, "B cannot declare '#m'; a superclass already has a private member by that name."},
+	}
+
+	for _, tt := range tests {
+		_, err := run(tt.source, false)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Fatalf("%s - expected error %q, got %v", tt.source, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WithInput makes readLine read from r instead of stdin.
+func WithInput(r io.Reader) Option {
+	return func(i *Interpreter) {
+		i.in = bufio.NewReader(r)
+	}
+}
+
+// WithFSRoot confines the file natives to the directory dir: paths are
+// taken relative to it and paths that would leave it, such as absolute ones
+// or ones starting with "..", fail. Symbolic links inside dir are followed.
+func WithFSRoot(dir string) Option {
+	return func(i *Interpreter) {
+		i.fsRoot = dir
+	}
+}
+
+// path returns where name is, taking the filesystem root into account.
+func (i *Interpreter) path(name string) (string, error) {
+	if i.fsRoot == "" {
+		return name, nil
+	}
+	if !filepath.IsLocal(name) && filepath.Clean(name) != "." {
+		return "", &fs.PathError{Op: "open", Path: name, Err: errors.New("path escapes the filesystem root")}
+	}
+	return filepath.Join(i.fsRoot, name), nil
+}
+
+func (i *Interpreter) openFile(name string, flag int) (*os.File, error) {
+	path, err := i.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, flag, 0644)
+}
+
+func (i *Interpreter) stat(name string) (fs.FileInfo, error) {
+	path, err := i.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(path)
+}
+
+// writeTo puts s in the file at path, opened with flag.
+func writeTo(interpreter *Interpreter, name string, flag int, arguments []any) any {
+	path, s := stringArg(name, arguments, 0), stringArg(name, arguments, 1)
+	f, err := interpreter.openFile(path, flag)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %v", name, err))
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(s); err != nil {
+		panic(fmt.Sprintf("%s: %v", name, err))
+	}
+	return nil
+}
+
+func init() {
+	register(CAP_FS, &native{name: "readFile", params: 1, doc: "Returns the contents of a file, or nil when it does not exist.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			f, err := interpreter.openFile(stringArg("readFile", arguments, 0), os.O_RDONLY)
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				panic(fmt.Sprintf("readFile: %v", err))
+			}
+			defer f.Close()
+
+			b, err := io.ReadAll(f)
+			if err != nil {
+				panic(fmt.Sprintf("readFile: %v", err))
+			}
+			interpreter.allocate()
+			return string(b)
+		}})
+
+	register(CAP_FS, &native{name: "writeFile", params: 2, doc: "Replaces the contents of a file with a string, creating the file if needed.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			return writeTo(interpreter, "writeFile", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, arguments)
+		}})
+
+	register(CAP_FS, &native{name: "appendFile", params: 2, doc: "Adds a string to the end of a file, creating the file if needed.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			return writeTo(interpreter, "appendFile", os.O_WRONLY|os.O_CREATE|os.O_APPEND, arguments)
+		}})
+
+	register(CAP_FS, &native{name: "exists", params: 1, doc: "Returns whether a file or directory exists.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			_, err := interpreter.stat(stringArg("exists", arguments, 0))
+			return err == nil
+		}})
+
+	register(CAP_FS, &native{name: "listDir", params: 1, doc: "Returns the names of the entries of a directory, sorted.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			f, err := interpreter.openFile(stringArg("listDir", arguments, 0), os.O_RDONLY)
+			if err != nil {
+				panic(fmt.Sprintf("listDir: %v", err))
+			}
+			defer f.Close()
+
+			entries, err := f.ReadDir(-1)
+			if err != nil {
+				panic(fmt.Sprintf("listDir: %v", err))
+			}
+			names := make([]string, len(entries))
+			for n, entry := range entries {
+				names[n] = entry.Name()
+			}
+			sort.Strings(names)
+			return nameList(interpreter, names)
+		}})
+
+	register(CAP_IO, &native{name: "readLine", params: 0, doc: "Returns the next line of input without its line break, or nil at the end.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			line, err := interpreter.in.ReadString('\n')
+			if err != nil && err != io.EOF {
+				panic(fmt.Sprintf("readLine: %v", err))
+			}
+			if err == io.EOF && line == "" {
+				return nil
+			}
+			interpreter.allocate()
+			return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		}})
+}
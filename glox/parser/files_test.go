@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileNatives(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	source := `print exists("notes.txt");
+print readFile("notes.txt");
+writeFile("notes.txt", "one\n");
+appendFile("notes.txt", "two\n");
+print exists("notes.txt");
+print split(readFile("notes.txt"), "\n");
+writeFile("sub/inner.txt", "x");
+print listDir(".");
+print listDir("sub");
+`
+	// the scanner has no escapes, so the script spells line breaks with \n
+	source = strings.ReplaceAll(source, `\n`, "\n")
+	expected := `false
+nil
+true
+["one", "two", ""]
+["notes.txt", "sub"]
+["inner.txt"]
+`
+
+	var b bytes.Buffer
+	stmts := parse(source)
+	i := NewInterpreter(stmts, WithOutput(&b), WithFSRoot(dir))
+	i.Interpret(stmts)
+	if b.String() != expected {
+		t.Fatalf("output wrong, expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+
+	written, err := os.ReadFile(filepath.Join(dir, "notes.txt"))
+	if err != nil || string(written) != "one\ntwo\n" {
+		t.Fatalf("notes.txt wrong, got %q, %v", written, err)
+	}
+}
+
+func TestFileNativesStayInRoot(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, source := range []string{
+		`readFile("../outside.txt");`,
+		`writeFile("/tmp/outside.txt", "x");`,
+		`listDir("sub/../..");`,
+	} {
+		stmts := parse(source)
+		i := NewInterpreter(stmts, WithFSRoot(dir))
+
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if err == nil || !strings.Contains(err.Error(), "path escapes the filesystem root") {
+					t.Fatalf("%s - expected the path to be rejected, got %v", source, err)
+				}
+			}()
+			i.Interpret(stmts)
+		}()
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	var b bytes.Buffer
+	stmts := parse(`var line = readLine();
+while (line != nil) {
+  print upper(line);
+  line = readLine();
+}`)
+	i := NewInterpreter(stmts, WithOutput(&b), WithInput(strings.NewReader("first\r\nsecond\nlast")))
+	NewResolver(i).ResolveStmts(stmts)
+	i.Interpret(stmts)
+
+	if expected := "FIRST\nSECOND\nLAST\n"; b.String() != expected {
+		t.Fatalf("output wrong, expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
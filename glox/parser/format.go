@@ -0,0 +1,463 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
+)
+
+const indentWidth = "    "
+
+// Formatter re-emits a parsed program with canonical indentation, spacing
+// and brace style. Comments are taken from the token trivia: a comment
+// that trails the last token of a statement stays on that line, any other
+// comment is written on its own line before the statement it was found in.
+type Formatter struct {
+	tokens []token.Token
+	out    strings.Builder
+	indent int
+
+	// comment slots are numbered 2*i for the leading and 2*i+1 for the
+	// trailing comments of tokens[i]; every slot below pending is written
+	pending int
+
+	lastLine int
+	fresh    bool
+
+	// prefix is written after the indentation of the next statement, for
+	// the "export" in front of a declaration
+	prefix string
+}
+
+// Format returns the canonical source for stmts, which must have been
+// parsed from tokens.
+func Format(tokens []token.Token, stmts []Stmt) string {
+	f := &Formatter{tokens: tokens}
+	for _, s := range stmts {
+		s.Accept(f)
+	}
+	f.comments(2*len(tokens), math.MaxInt)
+	return f.out.String()
+}
+
+func (f *Formatter) gap(line int) {
+	if !f.fresh && f.lastLine > 0 && line > f.lastLine+1 {
+		f.out.WriteString("\n")
+	}
+	f.fresh = false
+	if line > f.lastLine {
+		f.lastLine = line
+	}
+}
+
+func (f *Formatter) writeIndent() {
+	f.out.WriteString(strings.Repeat(indentWidth, f.indent))
+}
+
+func (f *Formatter) slot(n int) []token.Comment {
+	if n/2 >= len(f.tokens) {
+		return nil
+	}
+	if n%2 == 0 {
+		return f.tokens[n/2].Leading
+	}
+	return f.tokens[n/2].Trailing
+}
+
+// comments writes every pending comment slot below upto on its own line.
+// Comments found after clamp (inside the statement about to be written)
+// are treated as if they were on that line.
+func (f *Formatter) comments(upto int, clamp int) {
+	for ; f.pending < upto; f.pending++ {
+		for _, c := range f.slot(f.pending) {
+			line := min(c.Line, clamp)
+			f.gap(line)
+			f.writeIndent()
+			f.out.WriteString(c.Text)
+			f.out.WriteString("\n")
+			f.lastLine = max(f.lastLine, line+strings.Count(c.Text, "\n"))
+		}
+	}
+}
+
+// begin starts the line of a statement whose first token is first, writing
+// the comments found up to (and leading) the token at headerEnd first.
+func (f *Formatter) begin(first int, headerEnd int) {
+	line := f.tokens[first].Line
+	f.comments(2*headerEnd+1, line)
+	f.gap(line)
+	f.writeIndent()
+	f.out.WriteString(f.prefix)
+	f.prefix = ""
+}
+
+// finish ends the current line with the comments trailing tokens[last].
+func (f *Formatter) finish(last int) {
+	f.trailing(last)
+	f.out.WriteString("\n")
+	f.lastLine = max(f.lastLine, f.tokens[last].Line)
+}
+
+func (f *Formatter) trailing(at int) {
+	for _, c := range f.slot(2*at + 1) {
+		f.out.WriteString(" ")
+		f.out.WriteString(c.Text)
+		f.lastLine = max(f.lastLine, c.Line+strings.Count(c.Text, "\n"))
+	}
+	if f.pending < 2*at+2 {
+		f.pending = 2*at + 2
+	}
+}
+
+// body writes "{", the statements of a block and its closing "}" but not
+// the end of that line, so callers can continue it with "else".
+func (f *Formatter) body(open int, stmts []Stmt, close int) {
+	f.out.WriteString("{")
+	pending := f.pending
+	f.trailing(open)
+	f.out.WriteString("\n")
+	f.lastLine = max(f.lastLine, f.tokens[open].Line)
+
+	f.indent++
+	f.fresh = true
+	// comments skipped over by the "{" line move into the block
+	f.pending, pending = pending, f.pending
+	f.comments(2*open+1, f.tokens[open].Line)
+	f.pending = pending
+	for _, s := range stmts {
+		s.Accept(f)
+	}
+	f.comments(2*close+1, f.tokens[close].Line)
+	f.indent--
+	f.fresh = false
+
+	f.writeIndent()
+	f.out.WriteString("}")
+}
+
+// branch writes the body of an if, while or for statement whose header
+// has already been written up to ")".
+func (f *Formatter) branch(stmt Stmt) {
+	if b, ok := stmt.(*BlockStmt); ok {
+		f.out.WriteString(" ")
+		f.body(b.first, b.statments, b.last)
+		return
+	}
+	f.finish(stmt.span().first - 1)
+	f.indent++
+	stmt.Accept(f)
+	f.indent--
+}
+
+func (f *Formatter) headerEnd(body Stmt) int {
+	if _, ok := body.(*BlockStmt); ok {
+		return body.span().first
+	}
+	return body.span().first - 1
+}
+
+func (f *Formatter) expr(e Expr) string {
+	return e.Accept(f).(string)
+}
+
+func (f *Formatter) simple(s Stmt, text string) any {
+	sp := s.span()
+	f.begin(sp.first, sp.last)
+	f.out.WriteString(text)
+	f.finish(sp.last)
+	return nil
+}
+
+func (f *Formatter) varText(vs *VarStmt) string {
+	keyword := "var "
+	if vs.constant {
+		keyword = "const "
+	}
+	if vs.initializer == nil {
+		return keyword + vs.name.Lexeme + ";"
+	}
+	return keyword + vs.name.Lexeme + " = " + f.expr(vs.initializer) + ";"
+}
+
+func (f *Formatter) visitPrintStmt(s *PrintStmt) any {
+	return f.simple(s, "print "+f.expr(s.Expr)+";")
+}
+
+func (f *Formatter) visitExpressionStmt(s *ExprStmt) any {
+	return f.simple(s, f.expr(s.Expr)+";")
+}
+
+func (f *Formatter) visitVariableStmt(s *VarStmt) any {
+	return f.simple(s, f.varText(s))
+}
+
+func (f *Formatter) visitReturnStmt(s *ReturnStmt) any {
+	if s.value == nil {
+		return f.simple(s, "return;")
+	}
+	return f.simple(s, "return "+f.expr(s.value)+";")
+}
+
+func (f *Formatter) visitBlockStmt(s *BlockStmt) any {
+	f.begin(s.first, s.first-1)
+	f.body(s.first, s.statments, s.last)
+	f.finish(s.last)
+	return nil
+}
+
+func (f *Formatter) visitIfStmt(s *IfStmt) any {
+	f.begin(s.first, f.headerEnd(s.thenBranch))
+	f.ifChain(s)
+	return nil
+}
+
+func (f *Formatter) ifChain(s *IfStmt) {
+	f.out.WriteString("if (" + f.expr(s.condition) + ")")
+	f.branch(s.thenBranch)
+
+	if s.elseBranch == nil {
+		if _, ok := s.thenBranch.(*BlockStmt); ok {
+			f.finish(s.thenBranch.span().last)
+		}
+		return
+	}
+
+	if _, ok := s.thenBranch.(*BlockStmt); ok {
+		f.out.WriteString(" else")
+	} else {
+		f.begin(s.thenBranch.span().last+1, s.thenBranch.span().last+1)
+		f.out.WriteString("else")
+	}
+
+	switch e := s.elseBranch.(type) {
+	case *IfStmt:
+		f.out.WriteString(" ")
+		f.ifChain(e)
+	case *BlockStmt:
+		f.branch(e)
+		f.finish(e.last)
+	default:
+		f.branch(e)
+	}
+}
+
+func (f *Formatter) visitWhileStmt(s *WhileStmt) any {
+	f.begin(s.first, f.headerEnd(s.body))
+	f.out.WriteString("while (" + f.expr(s.condition) + ")")
+	f.branch(s.body)
+	if b, ok := s.body.(*BlockStmt); ok {
+		f.finish(b.last)
+	}
+	return nil
+}
+
+func (f *Formatter) visitForStmt(s *ForStmt) any {
+	f.begin(s.first, f.headerEnd(s.body))
+
+	var header strings.Builder
+	header.WriteString("for (")
+	switch init := s.initializer.(type) {
+	case nil:
+		header.WriteString(";")
+	case *VarStmt:
+		header.WriteString(f.varText(init))
+	case *ExprStmt:
+		header.WriteString(f.expr(init.Expr) + ";")
+	}
+	if s.condition != nil {
+		header.WriteString(" " + f.expr(s.condition))
+	}
+	header.WriteString(";")
+	if s.increment != nil {
+		header.WriteString(" " + f.expr(s.increment))
+	}
+	header.WriteString(")")
+	f.out.WriteString(header.String())
+
+	f.branch(s.body)
+	if b, ok := s.body.(*BlockStmt); ok {
+		f.finish(b.last)
+	}
+	return nil
+}
+
+func (f *Formatter) openBrace(s Stmt) int {
+	sp := s.span()
+	for i := sp.first; i <= sp.last; i++ {
+		if f.tokens[i].Type == token.LEFT_BRACE {
+			return i
+		}
+	}
+	return sp.last
+}
+
+func (f *Formatter) visitFunctionStmt(s *FunctionStmt) any {
+	open := f.openBrace(s)
+	f.begin(s.first, open)
+	if f.tokens[s.first].Type == token.FUN {
+		f.out.WriteString("fun ")
+	}
+
+	f.out.WriteString(signature(s) + " ")
+	f.body(open, s.body, s.last)
+	f.finish(s.last)
+	return nil
+}
+
+func (f *Formatter) visitClassStmt(s *ClassStmt) any {
+	open := f.openBrace(s)
+	f.begin(s.first, open)
+	if s.strict {
+		f.out.WriteString("strict ")
+	}
+	f.out.WriteString("class " + s.name.Lexeme + " ")
+	if s.superclass != nil {
+		f.out.WriteString("< " + s.superclass.name.Lexeme + " ")
+	}
+	f.with(s.traits)
+	f.members(open, s.fields, s.methods, s.last)
+	return nil
+}
+
+func (f *Formatter) visitTraitStmt(s *TraitStmt) any {
+	open := f.openBrace(s)
+	f.begin(s.first, open)
+	f.out.WriteString("trait " + s.name.Lexeme + " ")
+	f.with(s.traits)
+	f.members(open, nil, s.methods, s.last)
+	return nil
+}
+
+func (f *Formatter) with(traits []*Variable) {
+	if len(traits) > 0 {
+		f.out.WriteString("with " + strings.Join(traitNames(traits), ", ") + " ")
+	}
+}
+
+// members writes the body of a class or trait, its fields and methods in
+// the order they were written.
+func (f *Formatter) members(open int, fields []*VarStmt, methods []*FunctionStmt, close int) {
+	stmts := make([]Stmt, 0, len(fields)+len(methods))
+	for _, field := range fields {
+		stmts = append(stmts, field)
+	}
+	for _, m := range methods {
+		stmts = append(stmts, m)
+	}
+	sort.SliceStable(stmts, func(a, b int) bool {
+		return stmts[a].span().first < stmts[b].span().first
+	})
+
+	f.body(open, stmts, close)
+	f.finish(close)
+}
+
+func (f *Formatter) visitTryStmt(s *TryStmt) any {
+	f.begin(s.first, s.body.first)
+	f.out.WriteString("try ")
+	f.body(s.body.first, s.body.statments, s.body.last)
+	if s.catchBody != nil {
+		f.out.WriteString(" catch (" + s.catchName.Lexeme + ") ")
+		f.body(s.catchBody.first, s.catchBody.statments, s.catchBody.last)
+	}
+	if s.finallyBody != nil {
+		f.out.WriteString(" finally ")
+		f.body(s.finallyBody.first, s.finallyBody.statments, s.finallyBody.last)
+	}
+	f.finish(s.last)
+	return nil
+}
+
+func (f *Formatter) visitThrowStmt(s *ThrowStmt) any {
+	return f.simple(s, "throw "+f.expr(s.value)+";")
+}
+
+func (f *Formatter) visitImportStmt(s *ImportStmt) any {
+	if len(s.names) == 0 {
+		return f.simple(s, "import "+s.path.Lexeme+";")
+	}
+
+	names := make([]string, len(s.names))
+	for i, name := range s.names {
+		names[i] = name.Lexeme
+	}
+	return f.simple(s, "import { "+strings.Join(names, ", ")+" } from "+s.path.Lexeme+";")
+}
+
+func (f *Formatter) visitExportStmt(s *ExportStmt) any {
+	f.prefix = "export "
+	return s.declaration.Accept(f)
+}
+
+func (f *Formatter) VisitBinary(expr *Binary) any {
+	return f.expr(expr.Left) + " " + expr.Operator.Lexeme + " " + f.expr(expr.Right)
+}
+
+func (f *Formatter) VisitGrouping(expr *Grouping) any {
+	return "(" + f.expr(expr.Expression) + ")"
+}
+
+func (f *Formatter) VisitLiteral(expr *Literal) any {
+	switch v := expr.Value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return `"` + v + `"`
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func (f *Formatter) VisitUnary(expr *Unary) any {
+	return expr.Operator.Lexeme + f.expr(expr.Right)
+}
+
+func (f *Formatter) VisitVariable(expr *Variable) any {
+	return expr.name.Lexeme
+}
+
+func (f *Formatter) VisitAssign(expr *Assign) any {
+	return expr.name.Lexeme + " = " + f.expr(expr.value)
+}
+
+func (f *Formatter) VisitLogical(expr *Logical) any {
+	return f.expr(expr.left) + " " + expr.operator.Lexeme + " " + f.expr(expr.right)
+}
+
+func (f *Formatter) VisitCall(expr *CallExpr) any {
+	args := make([]string, len(expr.arguments))
+	for i, a := range expr.arguments {
+		args[i] = f.expr(a)
+	}
+	return f.expr(expr.callee) + "(" + strings.Join(args, ", ") + ")"
+}
+
+func (f *Formatter) VisitGet(expr *Get) any {
+	return f.expr(expr.object) + "." + expr.name.Lexeme
+}
+
+func (f *Formatter) VisitSet(expr *Set) any {
+	return f.expr(expr.object) + "." + expr.name.Lexeme + " = " + f.expr(expr.value)
+}
+
+func (f *Formatter) VisitIndex(expr *Index) any {
+	return f.expr(expr.object) + "[" + f.expr(expr.index) + "]"
+}
+
+func (f *Formatter) VisitIndexSet(expr *IndexSet) any {
+	return f.expr(expr.object) + "[" + f.expr(expr.index) + "] = " + f.expr(expr.value)
+}
+
+func (f *Formatter) VisitThis(expr *This) any {
+	return "this"
+}
+
+func (f *Formatter) VisitSuper(expr *Super) any {
+	return "super." + expr.method.Lexeme
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/scanner"
+)
+
+func format(source string) string {
+	s := scanner.NewScanner(source)
+	s.ScanTokens()
+	p := NewParser(s.GetTokens())
+	return Format(s.GetTokens(), p.Parse())
+}
+
+func TestFormatCanonical(t *testing.T) {
+	input := `// leading comment
+var  a=1;   // trailing
+fun add(x,y){return x+y;}
+
+
+class A<B{
+  // inside the class
+  m( ){ if(a<2) print  "small"; else {print -a;}
+    // end of m
+  }
+}
+for(var i=0;i<3;i=i+1) print i;
+while (a) { a = !a and false; }
+`
+	expected := `// leading comment
+var a = 1; // trailing
+fun add(x, y) {
+    return x + y;
+}
+
+class A < B {
+    // inside the class
+    m() {
+        if (a < 2)
+            print "small";
+        else {
+            print -a;
+        }
+        // end of m
+    }
+}
+for (var i = 0; i < 3; i = i + 1)
+    print i;
+while (a) {
+    a = !a and false;
+}
+`
+
+	if got := format(input); got != expected {
+		t.Fatalf("format wrong, expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	scripts, err := filepath.Glob("../scripts/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range scripts {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		once := format(string(source))
+		twice := format(once)
+		if once != twice {
+			t.Fatalf("%s - formatting is not idempotent, first:\n%s\nsecond:\n%s", path, once, twice)
+		}
+	}
+}
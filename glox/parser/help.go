@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type help struct {
+}
+
+func (h *help) arity() int {
+	return 1
+}
+
+// Call prints the doc comments of the function or class it is given.
+func (h *help) Call(interpreter *Interpreter, arguments []any) (returnVal any) {
+	fmt.Fprintln(interpreter.out, describe(arguments[0]))
+	return nil
+}
+
+func (h *help) String() string {
+	return "<native fn 'help'> prints the documentation of a function or class"
+}
+
+func describe(value any) string {
+	var sb strings.Builder
+
+	switch v := value.(type) {
+	case *Function:
+		sb.WriteString("fun " + signature(v.declaration))
+		writeHelp(&sb, v.declaration.doc, "    ")
+
+	case *Class:
+		superclass := ""
+		if v.superclass != nil {
+			superclass = v.superclass.name
+		}
+		traits := make([]string, len(v.traits))
+		for i, t := range v.traits {
+			traits[i] = t.name
+		}
+		sb.WriteString(classHeader(v.name, superclass, traits))
+		writeHelp(&sb, v.doc, "    ")
+		describeMembers(&sb, &v.members)
+
+	case *Trait:
+		sb.WriteString("trait " + v.name)
+		writeHelp(&sb, v.doc, "    ")
+		describeMembers(&sb, &v.members)
+
+	case *native:
+		sb.WriteString(v.String())
+		writeHelp(&sb, v.doc, "    ")
+
+	case fmt.Stringer:
+		sb.WriteString(v.String())
+
+	default:
+		sb.WriteString("no documentation for " + stringify(value))
+	}
+
+	return sb.String()
+}
+
+func describeMembers(sb *strings.Builder, m *members) {
+	functions := []*Function{}
+	for _, table := range m.tables() {
+		for _, f := range table {
+			functions = append(functions, f)
+		}
+	}
+	sort.SliceStable(functions, func(a, b int) bool {
+		return functions[a].declaration.name.Lexeme < functions[b].declaration.name.Lexeme
+	})
+
+	for _, f := range functions {
+		sb.WriteString("\n    " + signature(f.declaration))
+		writeHelp(sb, f.declaration.doc, "        ")
+	}
+}
+
+func writeHelp(sb *strings.Builder, doc string, indent string) {
+	for _, line := range strings.Split(doc, "\n") {
+		if line != "" {
+			sb.WriteString("\n" + indent + line)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package parser
 
 import (
+	"bufio"
+	"context"
 	"fmt"
-	"reflect"
+	"io"
+	"math/rand/v2"
+	"os"
 
 	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
 )
@@ -11,37 +15,141 @@ type Interpreter struct {
 	Statements  []Stmt
 	environment *Environment
 	globals     *Environment
-	locals      map[string]int
+	locals      map[Expr]int
+	out         io.Writer
+	in          *bufio.Reader
+	fsRoot      string
+
+	// frames is the Lox call stack, the script itself at the bottom, and
+	// line the line being run in the innermost call
+	frames   []frame
+	line     int
+	maxDepth int
+
+	ctx         context.Context
+	limits      Limits
+	steps       int
+	allocations int
+
+	capabilities []Capability
+	random       *rand.Rand
+
+	// module is the one being run, importing the files being run by
+	// unfinished imports and modules the ones that have finished;
+	// scriptDir is where the script itself is
+	module     *module
+	importing  []string
+	modules    map[string]*module
+	modulePath []string
+	scriptDir  string
+
+	errorClass *Class
 }
 
 type Return struct {
 	value any
 }
 
-func NewInterpreter(statements []Stmt) *Interpreter {
+// Option configures an Interpreter created by NewInterpreter.
+type Option func(*Interpreter)
+
+// WithOutput sends the output of print statements and natives to w
+// instead of stdout.
+func WithOutput(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.out = w
+	}
+}
+
+// WithArgs exposes the command line arguments of a script as the args list.
+func WithArgs(args []string) Option {
+	return func(i *Interpreter) {
+		elements := make([]any, len(args))
+		for n, arg := range args {
+			elements[n] = arg
+		}
+		i.globals.define("args", NewList(elements))
+	}
+}
+
+func NewInterpreter(statements []Stmt, options ...Option) *Interpreter {
 	e := NewEnvironment(nil)
-	e.define("clock", &clock{})
+	e.define("help", &help{})
+	e.define("args", NewList([]any{}))
 
 	i := &Interpreter{
 		Statements:  statements,
 		environment: e,
 		globals:     e,
-		locals:      map[string]int{},
+		locals:      map[Expr]int{},
+		out:         os.Stdout,
+		in:          bufio.NewReader(os.Stdin),
+		frames:      []frame{{}},
+		maxDepth:    DEFAULT_MAX_DEPTH,
+
+		capabilities: ALL_CAPABILITIES,
+		random:       defaultRandom(),
+
+		module:  &module{exports: map[string]any{}},
+		modules: map[string]*module{},
 	}
 
+	for _, option := range options {
+		option(i)
+	}
+	i.defineErrorClass()
+	i.defineNatives()
+
 	return i
 }
 
+// Interpret runs statements. A failure is raised as a panic with a
+// *RuntimeError.
 func (i *Interpreter) Interpret(statements []Stmt) {
+	defer i.catch()
 
 	for _, s := range statements {
-		s.Accept(i)
+		i.execute(s)
+	}
+}
 
+// Eval runs statements like Interpret and, when the last one is a bare
+// expression, returns its value so a REPL can echo it.
+func (i *Interpreter) Eval(statements []Stmt) (value any, isExpr bool) {
+	if len(statements) == 0 {
+		return nil, false
+	}
+
+	last := len(statements) - 1
+	i.Interpret(statements[:last])
+
+	if e, ok := statements[last].(*ExprStmt); ok {
+		defer i.catch()
+		return e.Expr.Accept(i), true
+	}
+	i.execute(statements[last])
+	return nil, false
+}
+
+// catch turns a failure of the running script into a *RuntimeError before
+// passing it on.
+func (i *Interpreter) catch() {
+	if r := recover(); r != nil {
+		panic(i.runtimeError(r))
 	}
 }
 
+// Globals returns a copy of the variables defined in the global scope.
+func (i *Interpreter) Globals() map[string]any {
+	globals := make(map[string]any, len(i.globals.values))
+	for name, value := range i.globals.values {
+		globals[name] = value
+	}
+	return globals
+}
+
 func (i *Interpreter) Resolve(expr Expr, depth int) {
-	i.locals[fmt.Sprintf("%v", expr)] = depth
+	i.locals[expr] = depth
 }
 
 func stringify(object any) string {
@@ -53,6 +161,9 @@ func stringify(object any) string {
 }
 
 func (i *Interpreter) VisitLiteral(expr *Literal) any {
+	for n := 0; n < expr.allocations; n++ {
+		i.allocate()
+	}
 	return expr.Value
 }
 
@@ -62,32 +173,33 @@ func (i *Interpreter) VisitGrouping(expr *Grouping) any {
 
 func (i *Interpreter) VisitUnary(expr *Unary) any {
 	right := expr.Right.Accept(i)
+	i.line = expr.Operator.Line
 
 	switch expr.Operator.Type {
 	case token.MINUS:
+		if result, ok := i.special(right, NEG_METHOD, expr.Operator.Line); ok {
+			return result
+		}
 		r, ok := right.(float64)
 		if !ok {
-			panic("VisitUnary MINUS type expr.Right was expected to be a float64 and was not")
+			panic("Operand of '-' must be a number.")
 		}
 		return -r
 
 	case token.BANG:
-		r, ok := right.(bool)
-		if !ok {
-			panic("VisitUnary BANG type expr.Right was expected to be a bool and was not")
-		}
-		return !isTruthy(r)
+		return !isTruthy(right)
 	}
 
 	return nil
 }
 
 func (i *Interpreter) VisitVariable(expr *Variable) any {
+	i.line = expr.name.Line
 	return i.lookUpVariable(expr.name, expr)
 }
 
 func (i *Interpreter) lookUpVariable(name *token.Token, expr Expr) any {
-	distance, ok := i.locals[fmt.Sprintf("%v", expr)]
+	distance, ok := i.locals[expr]
 	if !ok {
 		return i.globals.Get(name)
 	} else {
@@ -118,13 +230,17 @@ func (i *Interpreter) visitVariableStmt(vstmt *VarStmt) any {
 		value = vstmt.initializer.Accept(i)
 	}
 
+	if vstmt.constant {
+		i.environment.defineConstant(vstmt.name.Lexeme, value)
+		return nil
+	}
 	i.environment.define(vstmt.name.Lexeme, value)
 	return nil
 }
 
 func (i *Interpreter) visitPrintStmt(pstmt *PrintStmt) any {
 	value := pstmt.Expr.Accept(i)
-	fmt.Println(value)
+	fmt.Fprintln(i.out, i.Stringify(value))
 	return nil
 }
 
@@ -133,80 +249,56 @@ func (i *Interpreter) visitExpressionStmt(etmt *ExprStmt) any {
 	return nil
 }
 
-func checkNumberOperand(operator token.Token, operand any) (float64, error) {
-	f, ok := operand.(float64)
-	if !ok {
-		return 0, fmt.Errorf("%v Operand must be a number.", operator)
-	}
-	return f, nil
-}
-
 func (i *Interpreter) VisitBinary(expr *Binary) any {
 	left := expr.Left.Accept(i)
 	right := expr.Right.Accept(i)
+	i.line = expr.Operator.Line
+
+	if result, ok := i.overload(expr.Operator, left, right); ok {
+		return result
+	}
 
 	switch expr.Operator.Type {
 	case token.MINUS:
-
-		l, _ := checkNumberOperand(*expr.Operator, left)
-		r, _ := checkNumberOperand(*expr.Operator, right)
-
+		l, r := numberOperands(expr.Operator, left, right)
 		return l - r
 
 	case token.SLASH:
-		l, _ := checkNumberOperand(*expr.Operator, left)
-		r, _ := checkNumberOperand(*expr.Operator, right)
-
+		l, r := numberOperands(expr.Operator, left, right)
 		return l / r
 
 	case token.STAR:
-		l, _ := checkNumberOperand(*expr.Operator, left)
-		r, _ := checkNumberOperand(*expr.Operator, right)
-
+		l, r := numberOperands(expr.Operator, left, right)
 		return l * r
 
 	case token.PLUS:
-
-		switch right.(type) {
-		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
-			l, _ := checkNumberOperand(*expr.Operator, left)
-			r, _ := checkNumberOperand(*expr.Operator, right)
-			return l + r
-
-		case string:
-			r, ok := right.(string)
-			if !ok {
-				panic("VisitBinary PLUS type expr.Right was expected to be a string and was not")
+		switch l := left.(type) {
+		case float64:
+			if r, ok := right.(float64); ok {
+				return l + r
 			}
-			l, ok := left.(string)
-			if !ok {
-				panic("VisitBinary PLUS type expr.Left was expected to be a string and was not")
+		case string:
+			if r, ok := right.(string); ok {
+				i.allocate()
+				return l + r
 			}
-
-			return l + r
-
-		default:
-			panic("VisitBinary PLUS type expr.Right was expected to be a string or number and was not")
 		}
+		panic("Operands of '+' must be two numbers or two strings.")
 
 	case token.GREATER:
-		l, _ := checkNumberOperand(*expr.Operator, left)
-		r, _ := checkNumberOperand(*expr.Operator, right)
+		l, r := numberOperands(expr.Operator, left, right)
 		return l > r
 
 	case token.GREATER_EQUAL:
-		l, _ := checkNumberOperand(*expr.Operator, left)
-		r, _ := checkNumberOperand(*expr.Operator, right)
+		l, r := numberOperands(expr.Operator, left, right)
 		return l >= r
 
 	case token.LESS:
-		l, _ := checkNumberOperand(*expr.Operator, left)
-		r, _ := checkNumberOperand(*expr.Operator, right)
+		l, r := numberOperands(expr.Operator, left, right)
 		return l < r
 
 	case token.LESS_EQUAL:
-		l, _ := checkNumberOperand(*expr.Operator, left)
-		r, _ := checkNumberOperand(*expr.Operator, right)
+		l, r := numberOperands(expr.Operator, left, right)
 		return l <= r
 
 	case token.BANG_EQUAL:
@@ -230,32 +322,41 @@ func (i *Interpreter) VisitCall(expr *CallExpr) any {
 		arguments = append(arguments, arg.Accept(i))
 	}
 
+	i.line = expr.paren.Line
 	c, ok := callee.(LoxCallable)
 	if ok {
 		if len(arguments) != c.arity() {
-
-			panic(fmt.Sprintf("%v Expected %d arguments but got %d", expr.paren, c.arity(), len(arguments)))
+			panic(fmt.Sprintf("Expected %d arguments but got %d.", c.arity(), len(arguments)))
 		}
-		return c.Call(i, arguments)
+		return i.call(c, arguments, expr.paren.Line)
 	} else {
-		panic(fmt.Sprintf("tried to call uncallable object %s; can only call functions and classes", reflect.TypeOf(callee)))
+		panic(fmt.Sprintf("tried to call uncallable object %s; can only call functions and classes", i.Stringify(callee)))
 	}
 }
+
+// hasProperties is implemented by the values whose properties can be read
+// with '.'.
+type hasProperties interface {
+	Get(interpreter *Interpreter, name *token.Token) any
+}
+
 func (i *Interpreter) VisitGet(expr *Get) any {
 	obj := expr.object.Accept(i)
+	i.line = expr.name.Line
 
-	o, ok := obj.(*LoxInstance)
+	o, ok := obj.(hasProperties)
 	if !ok {
 		panic("only instances have properties")
 	}
 
-	return o.Get(expr.name)
+	return o.Get(i, expr.name)
 }
 
 func (i *Interpreter) VisitAssign(expr *Assign) any {
 	value := expr.value.Accept(i)
+	i.line = expr.name.Line
 
-	distance, ok := i.locals[fmt.Sprintf("%v", expr)]
+	distance, ok := i.locals[expr]
 	if !ok {
 		i.globals.Assign(expr.name, value)
 
@@ -263,7 +364,6 @@ func (i *Interpreter) VisitAssign(expr *Assign) any {
 		i.environment.AssignAt(distance, expr.name, value)
 	}
 
-	i.environment.Assign(expr.name, value)
 	return value
 }
 
@@ -297,6 +397,7 @@ func (i *Interpreter) VisitLogical(expr *Logical) any {
 
 func (i *Interpreter) VisitSet(expr *Set) any {
 	object := expr.object.Accept(i)
+	i.line = expr.name.Line
 
 	o, ok := object.(*LoxInstance)
 	if !ok {
@@ -304,6 +405,10 @@ func (i *Interpreter) VisitSet(expr *Set) any {
 	}
 
 	value := expr.value.Accept(i)
+	if setter, ok := o.lookup(expr.name.Lexeme, setters); ok {
+		i.call(setter.bind(o), []any{value}, expr.name.Line)
+		return nil
+	}
 	o.Set(expr.name, value)
 	return nil
 }
@@ -315,9 +420,9 @@ func (i *Interpreter) visitBlockStmt(block *BlockStmt) any {
 
 func (i *Interpreter) visitIfStmt(ifStmt *IfStmt) any {
 	if isTruthy(ifStmt.condition.Accept(i)) {
-		ifStmt.thenBranch.Accept(i)
+		i.execute(ifStmt.thenBranch)
 	} else if ifStmt.elseBranch != nil {
-		ifStmt.elseBranch.Accept(i)
+		i.execute(ifStmt.elseBranch)
 	}
 
 	return nil
@@ -343,6 +448,7 @@ func (i *Interpreter) visitClassStmt(cStmt *ClassStmt) any {
 		}
 	}
 
+	traits := i.traits(cStmt.traits)
 	i.environment.define(cStmt.name.Lexeme, nil)
 
 	if cStmt.superclass != nil {
@@ -350,12 +456,16 @@ func (i *Interpreter) visitClassStmt(cStmt *ClassStmt) any {
 		i.environment.define("super", superclass)
 	}
 
-	methods := map[string]*Function{}
+	class := NewClass(cStmt.name.Lexeme, sc, map[string]*Function{})
+	class.doc = cStmt.doc
+	class.fields = cStmt.fields
+	class.fieldScope = i.environment
+	class.strict = cStmt.strict
 	for _, m := range cStmt.methods {
-		methods[m.name.Lexeme] = NewFunciton(m, i.environment, m.name.Lexeme == "this")
+		class.add(m, i.environment)
 	}
-
-	class := NewClass(cStmt.name.Lexeme, sc, methods)
+	class.traits = compose(cStmt.name.Lexeme, &class.members, traits)
+	class.checkPrivates()
 
 	if superclass != nil {
 		i.environment = i.environment.enclosing
@@ -366,7 +476,30 @@ func (i *Interpreter) visitClassStmt(cStmt *ClassStmt) any {
 
 func (i *Interpreter) visitWhileStmt(while *WhileStmt) any {
 	for isTruthy(while.condition.Accept(i)) {
-		while.body.Accept(i)
+		i.line = while.keyword.Line
+		i.step()
+		i.execute(while.body)
+	}
+	return nil
+}
+
+func (i *Interpreter) visitForStmt(f *ForStmt) any {
+	prev := i.environment
+	defer func() {
+		i.environment = prev
+	}()
+
+	i.environment = NewEnvironment(prev)
+	if f.initializer != nil {
+		i.execute(f.initializer)
+	}
+	for f.condition == nil || isTruthy(f.condition.Accept(i)) {
+		i.line = f.keyword.Line
+		i.step()
+		i.execute(f.body)
+		if f.increment != nil {
+			f.increment.Accept(i)
+		}
 	}
 	return nil
 }
@@ -398,24 +531,31 @@ func (i *Interpreter) visitReturnStmt(r *ReturnStmt) any {
 }
 
 func (i *Interpreter) VisitThis(expr *This) any {
+	i.line = expr.keyword.Line
 	return i.lookUpVariable(expr.keyword, expr)
 }
 
 func (i *Interpreter) VisitSuper(expr *Super) any {
-	distance := i.locals[fmt.Sprintf("%v", expr)]
+	i.line = expr.method.Line
+	distance := i.locals[expr]
 	superclass, ok := i.environment.getAt(distance, "super").(*Class)
 	if !ok {
 		panic("superclass can only be a class")
 	}
-	object, ok := i.environment.getAt(distance-1, "this").(*LoxInstance)
-	if !ok {
-		panic("this can only be a class instance")
+	var method *Function
+	switch object := i.environment.getAt(distance-1, "this").(type) {
+	case *LoxInstance:
+		method, _ = superclass.findMethod(expr.method.Lexeme)
+	case *Class:
+		// super in a static method
+		method, _ = superclass.lookup(expr.method.Lexeme, statics)
+	default:
+		panic(fmt.Sprintf("this can only be a class or an instance, not %v", object))
 	}
-	method, ok := superclass.findMethod(expr.method.Lexeme)
 	if method == nil {
 		panic(fmt.Sprintf("Undefined property '%s'.", expr.method.Lexeme))
 	}
-	return method.bind(object)
+	return method.bind(i.environment.getAt(distance-1, "this"))
 
 }
 
@@ -427,25 +567,37 @@ func (i *Interpreter) executeBlock(statements []Stmt, env *Environment) any {
 
 	i.environment = env
 	for _, statement := range statements {
-
-		statement.Accept(i)
+		i.execute(statement)
 	}
 
 	return nil
 }
 
+// isEqual compares numbers, strings, booleans and nil by value and
+// everything else by identity. Binding the same method to the same receiver
+// twice gives equal bound methods.
 func isEqual(a any, b any) bool {
-	if a == nil && b == nil {
-		return true
-	} else if a == nil || b == nil {
-		return false
-	} else {
-		aType := reflect.TypeOf(a)
-		bType := reflect.TypeOf(b)
-		if aType != bType {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case float64:
+		b, ok := b.(float64)
+		return ok && a == b
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	case bool:
+		b, ok := b.(bool)
+		return ok && a == b
+	case *Function:
+		b, ok := b.(*Function)
+		if !ok {
 			return false
 		}
-		return reflect.DeepEqual(a, b)
-
+		if a.method != nil && b.method != nil {
+			return a.method == b.method && a.receiver == b.receiver
+		}
+		return a == b
 	}
+	return a == b
 }
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+)
+
+// how many steps run between two checks of the interpreter's context
+const contextCheckInterval = 256
+
+// Limits bound what a script may do before it is stopped with a
+// LimitExceeded error. A zero field means no limit. How deep calls may nest
+// is set with WithMaxDepth.
+type Limits struct {
+	// MaxSteps is how many statements, loop iterations and function calls
+	// may run in total
+	MaxSteps int
+	// MaxAllocations is how many instances, lists and strings may be created
+	// while running
+	MaxAllocations int
+}
+
+// LimitExceeded is the error a script stops with when it goes over one of
+// its Limits or its context is done.
+type LimitExceeded struct {
+	// Limit is "steps", "allocations" or "context"
+	Limit string
+	Max   int
+	// Err is the context's error when Limit is "context"
+	Err error
+}
+
+func (e *LimitExceeded) Error() string {
+	if e.Err != nil {
+		return "execution stopped: " + e.Err.Error()
+	}
+	return fmt.Sprintf("limit exceeded: more than %d %s", e.Max, e.Limit)
+}
+
+func (e *LimitExceeded) Unwrap() error {
+	return e.Err
+}
+
+// WithContext stops the script once ctx is cancelled or past its deadline.
+func WithContext(ctx context.Context) Option {
+	return func(i *Interpreter) {
+		i.ctx = ctx
+	}
+}
+
+// WithLimits bounds the steps and allocations of a script.
+func WithLimits(limits Limits) Option {
+	return func(i *Interpreter) {
+		i.limits = limits
+	}
+}
+
+// step counts one unit of work against MaxSteps and now and then checks
+// whether the context is done.
+func (i *Interpreter) step() {
+	i.steps++
+	if i.limits.MaxSteps > 0 && i.steps > i.limits.MaxSteps {
+		panic(&LimitExceeded{Limit: "steps", Max: i.limits.MaxSteps})
+	}
+
+	if i.ctx != nil && i.steps%contextCheckInterval == 0 {
+		if err := i.ctx.Err(); err != nil {
+			panic(&LimitExceeded{Limit: "context", Err: err})
+		}
+	}
+}
+
+// allocate counts a new instance, list or string against MaxAllocations.
+func (i *Interpreter) allocate() {
+	i.allocations++
+	if i.limits.MaxAllocations > 0 && i.allocations > i.limits.MaxAllocations {
+		panic(&LimitExceeded{Limit: "allocations", Max: i.limits.MaxAllocations})
+	}
+}
+
+// execute runs one statement as a step of the script.
+func (i *Interpreter) execute(s Stmt) {
+	i.step()
+	s.Accept(i)
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func runLimited(source string, options ...Option) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err, _ = r.(error)
+		}
+	}()
+
+	stmts := parse(source)
+	i := NewInterpreter(stmts, append([]Option{WithOutput(io.Discard)}, options...)...)
+	NewResolver(i).ResolveStmts(stmts)
+	i.Interpret(stmts)
+	return nil
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		limits Limits
+		limit  string
+	}{
+		{"steps", "while (true) {}", Limits{MaxSteps: 1000}, "steps"},
+		{"recursion steps", "fun f() { f(); } f();", Limits{MaxSteps: 1000}, "steps"},
+		{"instances", "class A {} while (true) A();", Limits{MaxAllocations: 10}, "allocations"},
+		{"strings", `var s = ""; while (true) s = s + "a";`, Limits{MaxAllocations: 10}, "allocations"},
+		{"not catchable", `fun f() { while (true) {} } try { f(); } catch (e) { print e; }`, Limits{MaxSteps: 1000}, "steps"},
+	}
+
+	for _, tt := range tests {
+		err := runLimited(tt.source, WithLimits(tt.limits))
+
+		var le *LimitExceeded
+		if !errors.As(err, &le) || le.Limit != tt.limit {
+			t.Fatalf("%s - expected the %s limit to be exceeded, got %v", tt.name, tt.limit, err)
+		}
+	}
+
+	if err := runLimited("for (var i = 0; i < 10; i = i + 1) print i;", WithLimits(Limits{MaxSteps: 100})); err != nil {
+		t.Fatalf("script within its limits failed: %v", err)
+	}
+}
+
+func TestContextStopsScript(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := runLimited("while (true) {}", WithContext(ctx))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to stop the script, got %v", err)
+	}
+	var le *LimitExceeded
+	if !errors.As(err, &le) {
+		t.Fatalf("expected a LimitExceeded error, got %T", err)
+	}
+}
+
+func TestContextStopReportsTheLoopLine(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := runLimited("var i = 0;\nwhile (true) {\n  i = i + 1;\n}", WithContext(ctx))
+	var re *RuntimeError
+	if !errors.As(err, &re) || re.Line != 2 || re.Trace[0] != "[line 2] in script" {
+		t.Fatalf("expected the stop to be reported on line 2, got %v", err)
+	}
+
+	err = runLimited("for (;;) {}", WithContext(ctx))
+	if !errors.As(err, &re) || re.Line != 1 {
+		t.Fatalf("expected the stop to be reported on line 1, got %v", err)
+	}
+}
+
+func TestFoldedStringsStillCountAsAllocations(t *testing.T) {
+	sources := []string{
+		`for (var i = 0; i < 5; i = i + 1) print "a" + "b" + "c";`,
+		`for (var i = 0; i < 5; i = i + 1) { "a" + "b"; }`,
+		`for (var i = 0; i < 5; i = i + 1) if ("a" + "b" == "ab") print i;`,
+		`for (var i = 0; i < 5; i = i + 1) print !("a" + "b" != "ab") and i;`,
+		`var n = 0; while ("a" + "b" == "ab" and n < 5) n = n + 1;`,
+	}
+
+	for _, source := range sources {
+		counts := [2]int{}
+		for n, optimize := range []bool{false, true} {
+			stmts := parse(source)
+			i := NewInterpreter(stmts, WithOutput(io.Discard))
+			NewResolver(i).ResolveStmts(stmts)
+			if optimize {
+				stmts = Optimize(stmts)
+			}
+			i.Interpret(stmts)
+			counts[n] = i.allocations
+		}
+		if counts[0] == 0 || counts[0] != counts[1] {
+			t.Fatalf("%s - expected the same allocations with and without -O, got %d and %d", source, counts[0], counts[1])
+		}
+	}
+}
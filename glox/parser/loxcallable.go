@@ -9,6 +9,11 @@ type Function struct {
 	declaration *FunctionStmt
 	closure     *Environment
 	isInit      bool
+
+	// method and receiver are set on a bound method: the unbound method and
+	// the value this is bound to.
+	method   *Function
+	receiver any
 }
 
 func NewFunciton(declaration *FunctionStmt, closure *Environment, isInit bool) *Function {
@@ -19,19 +24,23 @@ func NewFunciton(declaration *FunctionStmt, closure *Environment, isInit bool) *
 	}
 }
 
-func (f *Function) bind(instance *LoxInstance) *Function {
+// bind returns f with this set to instance, or to the class for a static
+// method.
+func (f *Function) bind(instance any) *Function {
 	env := NewEnvironment(f.closure)
 	env.define("this", instance)
-	return NewFunciton(f.declaration, env, f.isInit)
+	bound := NewFunciton(f.declaration, env, f.isInit)
+	bound.method, bound.receiver = f, instance
+	return bound
 }
 
 func (f *Function) Call(interpreter *Interpreter, arguments []any) (returnVal any) {
+	// globals are those of the module the function was declared in
+	globals := interpreter.globals
+	interpreter.globals = f.closure.root()
 	defer func() {
+		interpreter.globals = globals
 		if err := recover(); err != nil {
-			if f.isInit {
-				returnVal = f.closure.getAt(0, "this")
-				return
-			}
 			if v, ok := err.(Return); ok {
 				if f.isInit {
 					returnVal = f.closure.getAt(0, "this")
@@ -45,6 +54,7 @@ func (f *Function) Call(interpreter *Interpreter, arguments []any) (returnVal an
 		}
 	}()
 
+	interpreter.step()
 	env := NewEnvironment(f.closure)
 
 	for i, v := range f.declaration.params {
@@ -53,6 +63,9 @@ func (f *Function) Call(interpreter *Interpreter, arguments []any) (returnVal an
 
 	interpreter.executeBlock(f.declaration.body, env)
 
+	if f.isInit {
+		return f.closure.getAt(0, "this")
+	}
 	return nil
 }
 
@@ -10,40 +10,100 @@ const (
 	NONE classType = iota
 	CLASS
 	SUBCLASS
+	TRAIT
 )
 
 type Class struct {
-	name       string
-	fields     map[string]any
-	methods    map[string]*Function
+	name string
+	members
 	superclass *Class
+	// traits are the ones the class was declared with, and the traits
+	// those are made of
+	traits []*Trait
+	// fields are set on every new instance, their initializers run in
+	// fieldScope
+	fields     []*VarStmt
+	fieldScope *Environment
+	strict     bool
+	doc        string
+}
+
+// members are the functions declared in a class or trait, by kind.
+type members struct {
+	methods map[string]*Function
+	getters map[string]*Function
+	setters map[string]*Function
+	statics map[string]*Function
+}
+
+func newMembers(methods map[string]*Function) members {
+	return members{
+		methods: methods,
+		getters: map[string]*Function{},
+		setters: map[string]*Function{},
+		statics: map[string]*Function{},
+	}
+}
+
+// tables returns the member maps, in the order help lists them.
+func (m *members) tables() []map[string]*Function {
+	return []map[string]*Function{m.statics, m.methods, m.getters, m.setters}
+}
+
+// add puts the function declared by declaration in the table for its kind.
+func (m *members) add(declaration *FunctionStmt, closure *Environment) {
+	name := declaration.name.Lexeme
+	switch {
+	case declaration.static:
+		m.statics[name] = NewFunciton(declaration, closure, false)
+	case declaration.getter:
+		m.getters[name] = NewFunciton(declaration, closure, false)
+	case declaration.setter:
+		m.setters[name] = NewFunciton(declaration, closure, false)
+	default:
+		m.methods[name] = NewFunciton(declaration, closure, name == "init")
+	}
 }
 
 type LoxInstance struct {
 	*Class
+	fields map[string]any
+	// a frozen instance's fields can no longer be set
+	frozen bool
 }
 
 func NewLoxInstance(c *Class) *LoxInstance {
 	return &LoxInstance{
-		c,
+		Class:  c,
+		fields: map[string]any{},
 	}
 }
 
-func (li *LoxInstance) Get(name *token.Token) any {
+func (li *LoxInstance) Get(interpreter *Interpreter, name *token.Token) any {
 	if v, ok := li.fields[name.Lexeme]; ok {
 		return v
 	}
 
+	if g, ok := li.lookup(name.Lexeme, getters); ok {
+		return interpreter.call(g.bind(li), []any{}, name.Line)
+	}
+
 	if m, ok := li.findMethod(name.Lexeme); ok {
 		return m.bind(li)
 	}
 
+	if li.strict {
+		li.undeclared(name.Lexeme)
+	}
 	panic("undefined property '" + name.Lexeme + "'.")
 }
 
 func (li *LoxInstance) Set(name *token.Token, value any) {
-	if li.fields == nil {
-		li.fields = make(map[string]interface{})
+	if li.frozen {
+		panic("cannot set '" + name.Lexeme + "' on a frozen " + li.String() + ".")
+	}
+	if li.strict && !li.declares(name.Lexeme) {
+		li.undeclared(name.Lexeme)
 	}
 	li.fields[name.Lexeme] = value
 }
@@ -55,12 +115,35 @@ func (li *LoxInstance) String() string {
 func NewClass(name string, superclass *Class, methods map[string]*Function) *Class {
 	return &Class{
 		name:       name,
-		fields:     map[string]any{},
-		methods:    methods,
+		members:    newMembers(methods),
 		superclass: superclass,
 	}
 }
 
+func getters(c *Class) map[string]*Function { return c.getters }
+func setters(c *Class) map[string]*Function { return c.setters }
+func statics(c *Class) map[string]*Function { return c.statics }
+
+// lookup finds name among the members of c or of its superclasses that
+// pick picks.
+func (c *Class) lookup(name string, pick func(*Class) map[string]*Function) (*Function, bool) {
+	for ; c != nil; c = c.superclass {
+		if f, ok := pick(c)[name]; ok {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// Get returns a static method of the class, bound so that this is the
+// class it was called on.
+func (c *Class) Get(interpreter *Interpreter, name *token.Token) any {
+	if m, ok := c.lookup(name.Lexeme, statics); ok {
+		return m.bind(c)
+	}
+	panic("undefined property '" + name.Lexeme + "'.")
+}
+
 func (c *Class) findMethod(name string) (*Function, bool) {
 	var v *Function
 	var ok bool
@@ -71,20 +154,32 @@ func (c *Class) findMethod(name string) (*Function, bool) {
 	}
 
 	if c.superclass != nil {
-		v, ok = c.superclass.methods[name]
+		return c.superclass.findMethod(name)
 	}
 
 	return v, ok
 }
 
+// isSubclassOf reports whether c is class or inherits from it.
+func (c *Class) isSubclassOf(class *Class) bool {
+	for ; c != nil; c = c.superclass {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
 func (lc *Class) String() string {
 	return lc.name
 }
 
 func (lc *Class) Call(interpreter *Interpreter, arguments []any) any {
+	interpreter.allocate()
 	instance := NewLoxInstance(lc)
+	interpreter.initFields(lc, instance)
 
-	initializer, ok := lc.findMethod("this")
+	initializer, ok := lc.findMethod("init")
 	if ok {
 		initializer.bind(instance).Call(interpreter, arguments)
 	}
@@ -93,7 +188,7 @@ func (lc *Class) Call(interpreter *Interpreter, arguments []any) any {
 }
 
 func (lc *Class) arity() int {
-	initializer, ok := lc.findMethod("this")
+	initializer, ok := lc.findMethod("init")
 	if !ok {
 		return 0
 	}
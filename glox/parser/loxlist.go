@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
+)
+
+// List is an ordered sequence of Lox values, such as the script arguments
+// bound to args.
+type List struct {
+	elements []any
+}
+
+func NewList(elements []any) *List {
+	return &List{elements: elements}
+}
+
+func (l *List) index(name string, index any) int {
+	f, ok := index.(float64)
+	if !ok || f != float64(int(f)) {
+		panic(fmt.Sprintf("%s: list index must be a whole number.", name))
+	}
+	i := int(f)
+	if i < 0 || i >= len(l.elements) {
+		panic(fmt.Sprintf("%s: list index %d out of range.", name, i))
+	}
+	return i
+}
+
+// Get returns the length of the list or one of its methods.
+func (l *List) Get(interpreter *Interpreter, name *token.Token) any {
+	switch name.Lexeme {
+	case "length":
+		return float64(len(l.elements))
+
+	case "get":
+		return &native{name: "get", params: 1, fn: func(interpreter *Interpreter, arguments []any) any {
+			return l.elements[l.index("get", arguments[0])]
+		}}
+
+	case "set":
+		return &native{name: "set", params: 2, fn: func(interpreter *Interpreter, arguments []any) any {
+			l.elements[l.index("set", arguments[0])] = arguments[1]
+			return arguments[1]
+		}}
+
+	case "push":
+		return &native{name: "push", params: 1, fn: func(interpreter *Interpreter, arguments []any) any {
+			l.elements = append(l.elements, arguments[0])
+			return nil
+		}}
+
+	case "pop":
+		return &native{name: "pop", params: 0, fn: func(interpreter *Interpreter, arguments []any) any {
+			if len(l.elements) == 0 {
+				panic("pop: list is empty.")
+			}
+			last := l.elements[len(l.elements)-1]
+			l.elements = l.elements[:len(l.elements)-1]
+			return last
+		}}
+	}
+
+	panic("undefined property '" + name.Lexeme + "'.")
+}
+
+func (l *List) String() string {
+	return l.format(stringify)
+}
+
+// format writes the list with its elements turned to text by str, strings
+// in quotes.
+func (l *List) format(str func(any) string) string {
+	parts := make([]string, len(l.elements))
+	for i, e := range l.elements {
+		if s, ok := e.(string); ok {
+			parts[i] = `"` + s + `"`
+		} else {
+			parts[i] = str(e)
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
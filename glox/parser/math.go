@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// WithRandomSeed seeds random and randomInt so a script draws the same
+// numbers on every run.
+func WithRandomSeed(seed uint64) Option {
+	return func(i *Interpreter) {
+		i.random = newRandom(seed)
+	}
+}
+
+func newRandom(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+func init() {
+	registerConstant("PI", math.Pi)
+
+	registerCore(mathFunction("sqrt", "Returns the square root of a number.", math.Sqrt))
+	registerCore(mathFunction("floor", "Returns the greatest whole number less than or equal to a number.", math.Floor))
+	registerCore(mathFunction("ceil", "Returns the least whole number greater than or equal to a number.", math.Ceil))
+	registerCore(mathFunction("round", "Returns the nearest whole number, rounding half away from zero.", math.Round))
+	registerCore(mathFunction("abs", "Returns the absolute value of a number.", math.Abs))
+	registerCore(mathFunction("sin", "Returns the sine of an angle in radians.", math.Sin))
+	registerCore(mathFunction("cos", "Returns the cosine of an angle in radians.", math.Cos))
+	registerCore(mathFunction("tan", "Returns the tangent of an angle in radians.", math.Tan))
+	registerCore(mathFunction("log", "Returns the natural logarithm of a number.", math.Log))
+	registerCore(mathFunction("exp", "Returns e raised to a number.", math.Exp))
+
+	registerCore(mathFunction2("pow", "Returns a number raised to a power.", math.Pow))
+	registerCore(mathFunction2("min", "Returns the smaller of two numbers.", math.Min))
+	registerCore(mathFunction2("max", "Returns the larger of two numbers.", math.Max))
+
+	register(CAP_RANDOM, &native{name: "random", params: 0, doc: "Returns a random number from 0 up to, not including, 1.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			return interpreter.random.Float64()
+		}})
+
+	register(CAP_RANDOM, &native{name: "randomInt", params: 2, doc: "Returns a random whole number from lo to hi, both included.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			lo, hi := intArg("randomInt", arguments, 0), intArg("randomInt", arguments, 1)
+			if lo > hi {
+				panic(fmt.Sprintf("randomInt: lo %d is greater than hi %d.", lo, hi))
+			}
+			return float64(lo + interpreter.random.IntN(hi-lo+1))
+		}})
+
+	register(CAP_RANDOM, &native{name: "seed", params: 1, doc: "Seeds random and randomInt so they repeat the same numbers.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			interpreter.random = newRandom(uint64(intArg("seed", arguments, 0)))
+			return nil
+		}})
+}
+
+func defaultRandom() *rand.Rand {
+	return newRandom(uint64(time.Now().UnixNano()))
+}
+
+// mathFunction makes a native from a function of one number.
+func mathFunction(name string, doc string, f func(float64) float64) *native {
+	return &native{name: name, params: 1, doc: doc, fn: func(interpreter *Interpreter, arguments []any) any {
+		return f(numberArg(name, arguments, 0))
+	}}
+}
+
+// mathFunction2 makes a native from a function of two numbers.
+func mathFunction2(name string, doc string, f func(float64, float64) float64) *native {
+	return &native{name: name, params: 2, doc: doc, fn: func(interpreter *Interpreter, arguments []any) any {
+		return f(numberArg(name, arguments, 0), numberArg(name, arguments, 1))
+	}}
+}
@@ -0,0 +1,20 @@
+package parser
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWithRandomSeed(t *testing.T) {
+	draw := func() string {
+		var b bytes.Buffer
+		stmts := parse("print random(); print randomInt(1, 100);")
+		i := NewInterpreter(stmts, WithOutput(&b), WithRandomSeed(7))
+		i.Interpret(stmts)
+		return b.String()
+	}
+
+	if first, second := draw(), draw(); first != second {
+		t.Fatalf("seeded runs differ:\n%s\n%s", first, second)
+	}
+}
@@ -0,0 +1,247 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/scanner"
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
+)
+
+// module is a Lox file run by an import. Every module runs once, in its
+// own globals, and later imports of the same file share its exports.
+type module struct {
+	path    string
+	names   []string
+	exports map[string]any
+}
+
+// WithScriptPath names the file the script was read from, so its imports
+// are found relative to it and an import of the script itself is reported
+// as a cycle.
+func WithScriptPath(path string) Option {
+	return func(i *Interpreter) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		i.module.path = path
+		i.importing = []string{path}
+		i.scriptDir = filepath.Dir(path)
+	}
+}
+
+// WithModulePath adds directories to look for modules in when an import
+// is not found next to the importing file.
+func WithModulePath(dirs ...string) Option {
+	return func(i *Interpreter) {
+		i.modulePath = append(i.modulePath, dirs...)
+	}
+}
+
+// findModule returns the absolute path of the module an import of path
+// refers to from the module being run. A script without the fs capability,
+// or confined by WithFSRoot, can only import files inside its own
+// directory, the module path or the filesystem root, which is searched
+// last.
+func (i *Interpreter) findModule(path string) string {
+	candidates := []string{path}
+	if !filepath.IsAbs(path) {
+		dir := "."
+		if i.module.path != "" {
+			dir = filepath.Dir(i.module.path)
+		}
+		candidates = []string{filepath.Join(dir, path)}
+		for _, d := range i.modulePath {
+			candidates = append(candidates, filepath.Join(d, path))
+		}
+		if i.fsRoot != "" {
+			candidates = append(candidates, filepath.Join(i.fsRoot, path))
+		}
+	}
+
+	sandboxed := !i.HasCapability(CAP_FS) || i.fsRoot != ""
+	looked := []string{}
+	for _, c := range candidates {
+		if sandboxed && !i.importable(c) {
+			continue
+		}
+		looked = append(looked, c)
+		if info, err := os.Stat(c); err == nil && !info.IsDir() {
+			if abs, err := filepath.Abs(c); err == nil {
+				return abs
+			}
+			return c
+		}
+	}
+	if len(looked) == 0 {
+		panic(fmt.Sprintf("cannot import '%s'; it is outside the script's directory and the module path.", path))
+	}
+	panic(fmt.Sprintf("cannot find module '%s' (looked for %s).", path, strings.Join(looked, ", ")))
+}
+
+// importable reports whether file is inside a directory a sandboxed script
+// can import from.
+func (i *Interpreter) importable(file string) bool {
+	roots := append([]string{i.scriptDir}, i.modulePath...)
+	if i.fsRoot != "" {
+		roots = append(roots, i.fsRoot)
+	}
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return false
+	}
+	for _, root := range roots {
+		if root == "" {
+			root = "."
+		}
+		if root, err := filepath.Abs(root); err == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil && filepath.IsLocal(rel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// importModule runs the module at path unless it already ran and returns it.
+func (i *Interpreter) importModule(path string) *module {
+	file := i.findModule(path)
+	if m, ok := i.modules[file]; ok {
+		return m
+	}
+
+	for n, loading := range i.importing {
+		if loading == file {
+			cycle := []string{}
+			for _, f := range append(i.importing[n:], file) {
+				cycle = append(cycle, filepath.Base(f))
+			}
+			panic("import cycle: " + strings.Join(cycle, " -> "))
+		}
+	}
+
+	stmts := i.loadModule(path, file)
+
+	globals, environment, current := i.globals, i.environment, i.module
+	defer func() {
+		i.globals, i.environment, i.module = globals, environment, current
+		i.importing = i.importing[:len(i.importing)-1]
+	}()
+
+	m := &module{path: file, exports: map[string]any{}}
+	i.globals = NewEnvironment(nil)
+	i.globals.define("help", &help{})
+	i.globals.define("args", globals.values["args"])
+	i.environment = i.globals
+	i.module = m
+	i.importing = append(i.importing, file)
+	i.defineNatives()
+
+	for _, s := range stmts {
+		i.execute(s)
+	}
+
+	for _, name := range m.names {
+		m.exports[name] = i.globals.values[name]
+	}
+	i.modules[file] = m
+	return m
+}
+
+// moduleExports returns the names exported by the module an import of path
+// refers to, read from its source without running it.
+func (i *Interpreter) moduleExports(path string) (names []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	source, err := os.ReadFile(i.findModule(path))
+	if err != nil {
+		return nil, err
+	}
+
+	s := scanner.NewScanner(string(source))
+	s.ScanTokens()
+	if s.HadError() {
+		return nil, fmt.Errorf("could not scan the module")
+	}
+
+	for _, stmt := range NewParser(s.GetTokens()).Parse() {
+		if e, ok := stmt.(*ExportStmt); ok {
+			names = append(names, exportedName(e.declaration).Lexeme)
+		}
+	}
+	return names, nil
+}
+
+// loadModule scans, parses and resolves the module in file.
+func (i *Interpreter) loadModule(path string, file string) (stmts []Stmt) {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(fmt.Sprintf("in module '%s': %v", path, r))
+		}
+	}()
+
+	source, err := os.ReadFile(file)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	s := scanner.NewScanner(string(source))
+	s.ScanTokens()
+	if s.HadError() {
+		panic("could not scan the module")
+	}
+
+	stmts = NewParser(s.GetTokens()).Parse()
+	NewResolver(i).ResolveStmts(stmts)
+	return stmts
+}
+
+func (i *Interpreter) visitImportStmt(s *ImportStmt) any {
+	i.line = s.keyword.Line
+	path := s.path.Literal.(string)
+	m := i.importModule(path)
+
+	if len(s.names) == 0 {
+		for _, name := range m.names {
+			i.environment.define(name, m.exports[name])
+		}
+		return nil
+	}
+
+	for _, name := range s.names {
+		value, ok := m.exports[name.Lexeme]
+		if !ok {
+			panic(fmt.Sprintf("module '%s' does not export '%s'.", path, name.Lexeme))
+		}
+		i.environment.define(name.Lexeme, value)
+	}
+	return nil
+}
+
+func (i *Interpreter) visitExportStmt(s *ExportStmt) any {
+	i.execute(s.declaration)
+	i.module.names = append(i.module.names, exportedName(s.declaration).Lexeme)
+	return nil
+}
+
+// exportedName returns the name declared by the declaration of an export.
+func exportedName(declaration Stmt) *token.Token {
+	switch d := declaration.(type) {
+	case *VarStmt:
+		return d.name
+	case *FunctionStmt:
+		return d.name
+	case *ClassStmt:
+		return d.name
+	case *TraitStmt:
+		return d.name
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModules creates the files in a temporary directory and returns it.
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func runScript(path string, options ...Option) (out string, err error) {
+	var b bytes.Buffer
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = b.String(), fmt.Errorf("%v", r)
+		}
+	}()
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	stmts := parse(string(source))
+	options = append([]Option{WithOutput(&b), WithScriptPath(path)}, options...)
+	i := NewInterpreter(stmts, options...)
+	NewResolver(i).ResolveStmts(stmts)
+	i.Interpret(stmts)
+	return b.String(), nil
+}
+
+func TestImport(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.lox": `import "util.lox";
+import { Counter } from "lib/counter.lox";
+print double(21);
+var c = Counter();
+c.add(); c.add();
+print c.n;
+print hidden;
+`,
+		"util.lox": `print "loading util";
+var scale = 2;
+fun helper(n) { return n * scale; }
+export fun double(n) { return helper(n); }
+var hidden = "util";
+`,
+		"lib/counter.lox": `import { double } from "../util.lox";
+export class Counter {
+  init() { this.n = 0; }
+  add() { this.n = this.n + double(1); }
+}
+`,
+	})
+
+	out, err := runScript(filepath.Join(dir, "main.lox"))
+	if err == nil || !strings.Contains(err.Error(), "undefined variable 'hidden'") {
+		t.Fatalf("expected hidden to stay private to util.lox, got %v", err)
+	}
+	// util.lox runs once though it is imported twice
+	expected := "loading util\n42\n4\n"
+	if out != expected {
+		t.Fatalf("output wrong, expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{"missing module", map[string]string{
+			"main.lox": `import "nope.lox";`,
+		}, "cannot find module 'nope.lox'"},
+		{"missing name", map[string]string{
+			"main.lox": `import { b } from "a.lox";`,
+			"a.lox":    `export var a = 1; var b = 2;`,
+		}, "module 'a.lox' does not export 'b'."},
+		{"cycle", map[string]string{
+			"main.lox": `import "a.lox";`,
+			"a.lox":    `import "b.lox";`,
+			"b.lox":    `import "main.lox";`,
+		}, "import cycle: main.lox -> a.lox -> b.lox -> main.lox"},
+		{"parse error", map[string]string{
+			"main.lox": `import "a.lox";`,
+			"a.lox":    `export print 1;`,
+		}, "in module 'a.lox': "},
+	}
+
+	for _, tt := range tests {
+		dir := writeModules(t, tt.files)
+		_, err := runScript(filepath.Join(dir, "main.lox"))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Fatalf("%s - expected error containing %q, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestImportSearchPath(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"app/main.lox":     `import { greet } from "greet.lox"; print greet("lox");`,
+		"shared/greet.lox": `export fun greet(name) { return "hello " + name; }`,
+	})
+
+	out, err := runScript(filepath.Join(dir, "app", "main.lox"), WithModulePath(filepath.Join(dir, "shared")))
+	if err != nil || out != "hello lox\n" {
+		t.Fatalf("expected hello lox, got %q, %v", out, err)
+	}
+}
+
+func TestImportSandbox(t *testing.T) {
+	secret := writeModules(t, map[string]string{
+		"secret.lox": `export var secret = "hunter2";`,
+	})
+	dir := writeModules(t, map[string]string{
+		"app/main.lox":       `import { double } from "lib/double.lox"; print double(2);`,
+		"app/lib/double.lox": `import { scale } from "../scale.lox"; export fun double(n) { return n * scale; }`,
+		"app/scale.lox":      `export var scale = 2;`,
+		"app/absolute.lox":   fmt.Sprintf(`import %q; print secret;`, filepath.Join(secret, "secret.lox")),
+		"app/parent.lox":     `import "../outside.lox";`,
+		"outside.lox":        `this is not lox`,
+	})
+	app := filepath.Join(dir, "app")
+
+	sandboxes := map[string][]Option{
+		"without fs":  {WithCapabilities()},
+		"with a root": {WithFSRoot(app)},
+	}
+	for name, options := range sandboxes {
+		out, err := runScript(filepath.Join(app, "main.lox"), options...)
+		if err != nil || out != "4\n" {
+			t.Fatalf("%s - expected imports inside the script's directory to work, got %q, %v", name, out, err)
+		}
+
+		for _, script := range []string{"absolute.lox", "parent.lox"} {
+			out, err := runScript(filepath.Join(app, script), options...)
+			if err == nil || !strings.Contains(err.Error(), "is outside the script's directory and the module path") {
+				t.Fatalf("%s - expected %s to be refused, got %q, %v", name, script, out, err)
+			}
+			if strings.Contains(fmt.Sprint(err), "hunter2") || strings.Contains(fmt.Sprint(err), "not lox") || out != "" {
+				t.Fatalf("%s - %s leaked a file outside the sandbox: %q, %v", name, script, out, err)
+			}
+		}
+	}
+
+	out, err := runScript(filepath.Join(app, "absolute.lox"))
+	if err != nil || out != "hunter2\n" {
+		t.Fatalf("expected an unconfined script to import any file, got %q, %v", out, err)
+	}
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+)
+
+// native is a LoxCallable implemented in Go.
+type native struct {
+	name   string
+	params int
+	doc    string
+	fn     func(interpreter *Interpreter, arguments []any) any
+}
+
+func (n *native) arity() int {
+	return n.params
+}
+
+func (n *native) Call(interpreter *Interpreter, arguments []any) any {
+	return n.fn(interpreter, arguments)
+}
+
+func (n *native) String() string {
+	return "<native fn '" + n.name + "'>"
+}
+
+// The argument helpers return argument n of a native call, raising a
+// runtime error that names the native when it has the wrong type.
+
+func stringArg(name string, arguments []any, n int) string {
+	s, ok := arguments[n].(string)
+	if !ok {
+		panic(fmt.Sprintf("%s: argument %d must be a string.", name, n+1))
+	}
+	return s
+}
+
+func numberArg(name string, arguments []any, n int) float64 {
+	f, ok := arguments[n].(float64)
+	if !ok {
+		panic(fmt.Sprintf("%s: argument %d must be a number.", name, n+1))
+	}
+	return f
+}
+
+func intArg(name string, arguments []any, n int) int {
+	f, ok := arguments[n].(float64)
+	if !ok || f != math.Trunc(f) || math.IsInf(f, 0) {
+		panic(fmt.Sprintf("%s: argument %d must be a whole number.", name, n+1))
+	}
+	return int(f)
+}
+
+func listArg(name string, arguments []any, n int) *List {
+	l, ok := arguments[n].(*List)
+	if !ok {
+		panic(fmt.Sprintf("%s: argument %d must be a list.", name, n+1))
+	}
+	return l
+}
@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
+)
+
+// Instances take part in operators through methods of their class with
+// these names. A binary operator calls the method of its left operand with
+// the right one as argument; != is the negation of __eq. __str gives the
+// text print and str show, __index(i) reads instance[i] and
+// __setIndex(i, value) assigns it.
+var operatorMethods = map[token.TokenType]string{
+	token.PLUS:          "__add",
+	token.MINUS:         "__sub",
+	token.STAR:          "__mul",
+	token.SLASH:         "__div",
+	token.LESS:          "__lt",
+	token.LESS_EQUAL:    "__le",
+	token.GREATER:       "__gt",
+	token.GREATER_EQUAL: "__ge",
+	token.EQUAL_EQUAL:   "__eq",
+	token.BANG_EQUAL:    "__eq",
+}
+
+const (
+	NEG_METHOD       = "__neg"
+	STR_METHOD       = "__str"
+	INDEX_METHOD     = "__index"
+	SET_INDEX_METHOD = "__setIndex"
+)
+
+// special calls the method called name of value, when value is an instance
+// whose class has one.
+func (i *Interpreter) special(value any, name string, line int, arguments ...any) (any, bool) {
+	instance, ok := value.(*LoxInstance)
+	if !ok {
+		return nil, false
+	}
+	method, ok := instance.findMethod(name)
+	if !ok {
+		return nil, false
+	}
+	return i.call(method.bind(instance), arguments, line), true
+}
+
+// overload runs a binary operator whose left operand defines it.
+func (i *Interpreter) overload(operator *token.Token, left any, right any) (any, bool) {
+	name, ok := operatorMethods[operator.Type]
+	if !ok {
+		return nil, false
+	}
+
+	result, ok := i.special(left, name, operator.Line, right)
+	if ok && operator.Type == token.BANG_EQUAL {
+		return !isTruthy(result), true
+	}
+	return result, ok
+}
+
+func numberOperands(operator *token.Token, left any, right any) (float64, float64) {
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		panic(fmt.Sprintf("Operands of '%s' must be numbers.", operator.Lexeme))
+	}
+	return l, r
+}
+
+// Stringify returns the text print shows for value.
+func (i *Interpreter) Stringify(value any) string {
+	if l, ok := value.(*List); ok {
+		return l.format(i.Stringify)
+	}
+
+	s, ok := i.special(value, STR_METHOD, i.line)
+	if !ok {
+		return stringify(value)
+	}
+	text, ok := s.(string)
+	if !ok {
+		panic(fmt.Sprintf("%s must return a string.", STR_METHOD))
+	}
+	return text
+}
+
+func (i *Interpreter) VisitIndex(expr *Index) any {
+	object := expr.object.Accept(i)
+	index := expr.index.Accept(i)
+	i.line = expr.bracket.Line
+
+	switch o := object.(type) {
+	case *List:
+		return o.elements[o.index("[]", index)]
+	case string:
+		n, ok := index.(float64)
+		s := []rune(o)
+		if !ok || n != float64(int(n)) || n < 0 || int(n) >= len(s) {
+			panic(fmt.Sprintf("[]: index %s out of bounds for a string of length %d.", stringify(index), len(s)))
+		}
+		i.allocate()
+		return string(s[int(n)])
+	}
+
+	if value, ok := i.special(object, INDEX_METHOD, expr.bracket.Line, index); ok {
+		return value
+	}
+	panic("only lists, strings and instances with " + INDEX_METHOD + " can be indexed.")
+}
+
+func (i *Interpreter) VisitIndexSet(expr *IndexSet) any {
+	object := expr.object.Accept(i)
+	index := expr.index.Accept(i)
+	value := expr.value.Accept(i)
+	i.line = expr.bracket.Line
+
+	if l, ok := object.(*List); ok {
+		l.elements[l.index("[]", index)] = value
+		return value
+	}
+
+	if _, ok := i.special(object, SET_INDEX_METHOD, expr.bracket.Line, index, value); ok {
+		return value
+	}
+	panic("only lists and instances with " + SET_INDEX_METHOD + " can be assigned to by index.")
+}
@@ -0,0 +1,343 @@
+package parser
+
+import (
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
+)
+
+// Optimizer folds expressions whose operands are all literals and drops
+// code that can never run. It is meant to run after the Resolver, so it
+// changes nodes in place and never replaces a node the Resolver recorded.
+// Folding a string concatenation does not take it out of the allocation
+// limit: the literal it leaves still counts it, and is kept wherever the
+// script would have evaluated it.
+type Optimizer struct{}
+
+// Optimize returns stmts with constant expressions folded, if and while
+// statements with a constant condition reduced to the branch that runs and
+// the statements after a return or throw removed.
+func Optimize(stmts []Stmt) []Stmt {
+	return (&Optimizer{}).stmts(stmts)
+}
+
+func (o *Optimizer) stmts(stmts []Stmt) []Stmt {
+	result := []Stmt{}
+	for _, s := range stmts {
+		optimized := o.stmt(s)
+		if optimized == nil {
+			continue
+		}
+		result = append(result, optimized)
+
+		switch optimized.(type) {
+		case *ReturnStmt, *ThrowStmt:
+			return result
+		}
+	}
+	return result
+}
+
+// stmt returns the optimized statement, or nil when it does nothing.
+func (o *Optimizer) stmt(s Stmt) Stmt {
+	if s == nil {
+		return nil
+	}
+	optimized, _ := s.Accept(o).(Stmt)
+	return optimized
+}
+
+func (o *Optimizer) expr(e Expr) Expr {
+	if e == nil {
+		return nil
+	}
+	return e.Accept(o).(Expr)
+}
+
+func literal(e Expr) (any, bool) {
+	l, ok := e.(*Literal)
+	if !ok {
+		return nil, false
+	}
+	return l.Value, true
+}
+
+// constant is like literal but only for values that cost nothing to
+// evaluate, so code can stop evaluating them.
+func constant(e Expr) (any, bool) {
+	if l, ok := e.(*Literal); ok && l.allocations > 0 {
+		return nil, false
+	}
+	return literal(e)
+}
+
+// folded returns the literal an expression with the operands from folds to,
+// charged with their allocations and extra more.
+func folded(value any, extra int, from ...Expr) *Literal {
+	l := NewLiteralExpr(value)
+	l.allocations = extra
+	for _, e := range from {
+		l.allocations += e.(*Literal).allocations
+	}
+	return l
+}
+
+func (o *Optimizer) visitPrintStmt(s *PrintStmt) any {
+	s.Expr = o.expr(s.Expr)
+	return s
+}
+
+func (o *Optimizer) visitExpressionStmt(s *ExprStmt) any {
+	s.Expr = o.expr(s.Expr)
+	if _, ok := constant(s.Expr); ok {
+		return nil
+	}
+	return s
+}
+
+func (o *Optimizer) visitVariableStmt(s *VarStmt) any {
+	s.initializer = o.expr(s.initializer)
+	return s
+}
+
+func (o *Optimizer) visitReturnStmt(s *ReturnStmt) any {
+	s.value = o.expr(s.value)
+	return s
+}
+
+func (o *Optimizer) visitBlockStmt(s *BlockStmt) any {
+	s.statments = o.stmts(s.statments)
+	return s
+}
+
+func (o *Optimizer) visitIfStmt(s *IfStmt) any {
+	s.condition = o.expr(s.condition)
+	s.thenBranch = o.stmt(s.thenBranch)
+	s.elseBranch = o.stmt(s.elseBranch)
+
+	if value, ok := constant(s.condition); ok {
+		if isTruthy(value) {
+			return s.thenBranch
+		}
+		return s.elseBranch
+	}
+	if s.thenBranch == nil {
+		s.thenBranch = &BlockStmt{statments: []Stmt{}, stmtSpan: *s.span()}
+	}
+	return s
+}
+
+func (o *Optimizer) visitWhileStmt(s *WhileStmt) any {
+	s.condition = o.expr(s.condition)
+	if value, ok := constant(s.condition); ok && !isTruthy(value) {
+		return nil
+	}
+
+	s.body = o.stmt(s.body)
+	if s.body == nil {
+		s.body = &BlockStmt{statments: []Stmt{}, stmtSpan: *s.span()}
+	}
+	return s
+}
+
+func (o *Optimizer) visitForStmt(s *ForStmt) any {
+	s.initializer = o.stmt(s.initializer)
+	s.condition = o.expr(s.condition)
+	s.increment = o.expr(s.increment)
+	s.body = o.stmt(s.body)
+	if s.body == nil {
+		s.body = &BlockStmt{statments: []Stmt{}, stmtSpan: *s.span()}
+	}
+	return s
+}
+
+func (o *Optimizer) visitFunctionStmt(s *FunctionStmt) any {
+	s.body = o.stmts(s.body)
+	return s
+}
+
+func (o *Optimizer) visitClassStmt(s *ClassStmt) any {
+	for _, f := range s.fields {
+		f.Accept(o)
+	}
+	for _, m := range s.methods {
+		m.Accept(o)
+	}
+	return s
+}
+
+func (o *Optimizer) visitTraitStmt(s *TraitStmt) any {
+	for _, m := range s.methods {
+		m.Accept(o)
+	}
+	return s
+}
+
+func (o *Optimizer) visitTryStmt(s *TryStmt) any {
+	s.body.Accept(o)
+	if s.catchBody != nil {
+		s.catchBody.Accept(o)
+	}
+	if s.finallyBody != nil {
+		s.finallyBody.Accept(o)
+	}
+	return s
+}
+
+func (o *Optimizer) visitThrowStmt(s *ThrowStmt) any {
+	s.value = o.expr(s.value)
+	return s
+}
+
+func (o *Optimizer) visitImportStmt(s *ImportStmt) any {
+	return s
+}
+
+func (o *Optimizer) visitExportStmt(s *ExportStmt) any {
+	s.declaration.Accept(o)
+	return s
+}
+
+func (o *Optimizer) VisitBinary(expr *Binary) any {
+	expr.Left = o.expr(expr.Left)
+	expr.Right = o.expr(expr.Right)
+
+	left, lok := literal(expr.Left)
+	right, rok := literal(expr.Right)
+	if !lok || !rok {
+		return expr
+	}
+
+	switch expr.Operator.Type {
+	case token.EQUAL_EQUAL:
+		return folded(isEqual(left, right), 0, expr.Left, expr.Right)
+	case token.BANG_EQUAL:
+		return folded(!isEqual(left, right), 0, expr.Left, expr.Right)
+	}
+
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok && expr.Operator.Type == token.PLUS {
+			return folded(l+r, 1, expr.Left, expr.Right)
+		}
+		return expr
+	}
+
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return expr
+	}
+
+	switch expr.Operator.Type {
+	case token.PLUS:
+		return NewLiteralExpr(l + r)
+	case token.MINUS:
+		return NewLiteralExpr(l - r)
+	case token.STAR:
+		return NewLiteralExpr(l * r)
+	case token.SLASH:
+		return NewLiteralExpr(l / r)
+	case token.GREATER:
+		return NewLiteralExpr(l > r)
+	case token.GREATER_EQUAL:
+		return NewLiteralExpr(l >= r)
+	case token.LESS:
+		return NewLiteralExpr(l < r)
+	case token.LESS_EQUAL:
+		return NewLiteralExpr(l <= r)
+	}
+	return expr
+}
+
+func (o *Optimizer) VisitGrouping(expr *Grouping) any {
+	expr.Expression = o.expr(expr.Expression)
+	if _, ok := literal(expr.Expression); ok {
+		return expr.Expression
+	}
+	return expr
+}
+
+func (o *Optimizer) VisitLiteral(expr *Literal) any {
+	return expr
+}
+
+func (o *Optimizer) VisitUnary(expr *Unary) any {
+	expr.Right = o.expr(expr.Right)
+
+	value, ok := literal(expr.Right)
+	if !ok {
+		return expr
+	}
+
+	switch expr.Operator.Type {
+	case token.BANG:
+		return folded(!isTruthy(value), 0, expr.Right)
+	case token.MINUS:
+		if n, ok := value.(float64); ok {
+			return NewLiteralExpr(-n)
+		}
+	}
+	return expr
+}
+
+func (o *Optimizer) VisitLogical(expr *Logical) any {
+	expr.left = o.expr(expr.left)
+	expr.right = o.expr(expr.right)
+
+	value, ok := constant(expr.left)
+	if !ok {
+		return expr
+	}
+
+	if isTruthy(value) == (expr.operator.Type == token.OR) {
+		return expr.left
+	}
+	return expr.right
+}
+
+func (o *Optimizer) VisitVariable(expr *Variable) any {
+	return expr
+}
+
+func (o *Optimizer) VisitAssign(expr *Assign) any {
+	expr.value = o.expr(expr.value)
+	return expr
+}
+
+func (o *Optimizer) VisitCall(expr *CallExpr) any {
+	expr.callee = o.expr(expr.callee)
+	for i, arg := range expr.arguments {
+		expr.arguments[i] = o.expr(arg)
+	}
+	return expr
+}
+
+func (o *Optimizer) VisitGet(expr *Get) any {
+	expr.object = o.expr(expr.object)
+	return expr
+}
+
+func (o *Optimizer) VisitSet(expr *Set) any {
+	expr.object = o.expr(expr.object)
+	expr.value = o.expr(expr.value)
+	return expr
+}
+
+func (o *Optimizer) VisitIndex(expr *Index) any {
+	expr.object = o.expr(expr.object)
+	expr.index = o.expr(expr.index)
+	return expr
+}
+
+func (o *Optimizer) VisitIndexSet(expr *IndexSet) any {
+	expr.object = o.expr(expr.object)
+	expr.index = o.expr(expr.index)
+	expr.value = o.expr(expr.value)
+	return expr
+}
+
+func (o *Optimizer) VisitThis(expr *This) any {
+	return expr
+}
+
+func (o *Optimizer) VisitSuper(expr *Super) any {
+	return expr
+}
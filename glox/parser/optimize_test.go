@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/scanner"
+)
+
+func parse(source string) []Stmt {
+	s := scanner.NewScanner(source)
+	s.ScanTokens()
+	return NewParser(s.GetTokens()).Parse()
+}
+
+func run(source string, optimize bool) (out string, err error) {
+	var b bytes.Buffer
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = b.String(), fmt.Errorf("%v", r)
+		}
+	}()
+
+	stmts := parse(source)
+	i := NewInterpreter(stmts, WithOutput(&b))
+	NewResolver(i).ResolveStmts(stmts)
+	if optimize {
+		stmts = Optimize(stmts)
+	}
+	i.Interpret(stmts)
+	return b.String(), nil
+}
+
+func TestOptimizeFolds(t *testing.T) {
+	input := `print 1 + 2 * (3 - 1);
+print "a" + "b" == "ab";
+print !nil and x;
+print -x - -2;
+if (1 > 2) print "never"; else print "always";
+while (false) print "never";
+(1 + 1);
+fun f() {
+  return 1;
+  print "unreachable";
+}
+`
+	expected := `(print 5)
+(print true)
+(print x)
+(print (- (- x) -2))
+(print always)
+(fun f ()
+  (return 1))
+`
+
+	astp := AstPrinter{}
+	if got := astp.PrintStmts(Optimize(parse(input))); got != expected {
+		t.Fatalf("optimized tree wrong, expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestScriptsWithOptimizer(t *testing.T) {
+	scripts, err := filepath.Glob("../scripts/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range scripts {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the output of clock changes from run to run
+		if strings.Contains(string(source), "clock()") {
+			continue
+		}
+
+		expected, plainErr := run(string(source), false)
+		got, optimizedErr := run(string(source), true)
+		if (plainErr == nil) != (optimizedErr == nil) {
+			t.Fatalf("%s - error without the optimizer: %v, with it: %v", path, plainErr, optimizedErr)
+		}
+		if got != expected {
+			t.Fatalf("%s - optimized output wrong, expected:\n%s\ngot:\n%s", path, expected, got)
+		}
+	}
+}
+
+func TestOptimizeKeepsResolvedVariables(t *testing.T) {
+	source := `{
+  var a = 1;
+  fun f() {
+    a = (2 + 3) * a;
+    if (true) return a;
+  }
+  print f();
+  print a;
+}
+`
+	expected, _ := run(source, false)
+	got, err := run(source, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != expected {
+		t.Fatalf("optimized output wrong, expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/Martin-Martinez4/crafting-interpreters/glox/errorhandling"
 	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
@@ -30,18 +31,132 @@ func (p *Parser) Parse() []Stmt {
 }
 
 func (p *Parser) declaration() Stmt {
+	start := p.current
+	if p.match(token.IMPORT) {
+		return p.spanned(p.importDeclaration(), start)
+	}
+	if p.match(token.EXPORT) {
+		return p.spanned(p.exportDeclaration(), start)
+	}
+	// strict is only a keyword in front of class
+	strict := p.check(token.IDENTIFIER) && p.peek().Lexeme == "strict" &&
+		p.tokens[p.current+1].Type == token.CLASS
+	if strict {
+		p.advance()
+	}
 	if p.match(token.CLASS) {
-		return p.classDeclaration()
+		doc := docComment(&p.tokens[start])
+		c := p.classDeclaration()
+		c.doc = doc
+		c.strict = strict
+		return p.spanned(c, start)
+	}
+	if p.match(token.TRAIT) {
+		doc := docComment(p.previous())
+		t := p.traitDeclaration()
+		t.doc = doc
+		return p.spanned(t, start)
 	}
 	if p.match(token.FUN) {
-		return p.function("function")
+		doc := docComment(p.previous())
+		f := p.function("function")
+		f.doc = doc
+		return p.spanned(f, start)
 	}
-	if p.match(token.VAR) {
-		return p.varDeclaration()
+	if p.match(token.VAR, token.CONST) {
+		return p.spanned(p.varDeclaration(), start)
 	}
 	return p.statement()
 }
 
+func (p *Parser) importDeclaration() *ImportStmt {
+	keyword := p.previous()
+	names := []*token.Token{}
+
+	if p.match(token.LEFT_BRACE) {
+		for {
+			name, err := p.consume(token.IDENTIFIER, "Expect name to import.")
+			if err != nil {
+				panic(err.Error())
+			}
+			names = append(names, name)
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+
+		_, err := p.consume(token.RIGHT_BRACE, "Expect '}' after imported names.")
+		if err != nil {
+			panic(err.Error())
+		}
+		// from is only a keyword here, so it can still name variables
+		if !p.check(token.IDENTIFIER) || p.peek().Lexeme != "from" {
+			panic(fmt.Sprintf("%v Expect 'from' after imported names.", p.peek()))
+		}
+		p.advance()
+	}
+
+	path, err := p.consume(token.STRING, "Expect module path.")
+	if err != nil {
+		panic(err.Error())
+	}
+
+	_, err = p.consume(token.SEMICOLON, "Expect ';' after import.")
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return &ImportStmt{keyword: keyword, path: path, names: names}
+}
+
+func (p *Parser) exportDeclaration() *ExportStmt {
+	if !p.check(token.FUN) && !p.check(token.VAR) && !p.check(token.CONST) && !p.check(token.CLASS) && !p.check(token.TRAIT) {
+		panic(fmt.Sprintf("%v Expect 'fun', 'var', 'const', 'class' or 'trait' after 'export'.", p.peek()))
+	}
+
+	doc := docComment(p.previous())
+	declaration := p.declaration()
+	switch d := declaration.(type) {
+	case *FunctionStmt:
+		if d.doc == "" {
+			d.doc = doc
+		}
+	case *ClassStmt:
+		if d.doc == "" {
+			d.doc = doc
+		}
+	case *TraitStmt:
+		if d.doc == "" {
+			d.doc = doc
+		}
+	}
+
+	return &ExportStmt{declaration: declaration}
+}
+
+// docComment returns the text of the "///" comments written directly
+// before t, one line per comment. A blank line ends the block, so comments
+// above it are not t's.
+func docComment(t *token.Token) string {
+	lines := []string{}
+	line := t.Line - 1
+	for i := len(t.Leading) - 1; i >= 0 && t.Leading[i].Doc && t.Leading[i].Line == line; i-- {
+		text := strings.TrimPrefix(t.Leading[i].Text, "///")
+		lines = append([]string{strings.TrimPrefix(text, " ")}, lines...)
+		line--
+	}
+	return strings.Join(lines, "\n")
+}
+
+// spanned records that stmt was parsed from the tokens between start and
+// the last consumed token.
+func (p *Parser) spanned(stmt Stmt, start int) Stmt {
+	s := stmt.span()
+	s.first = start
+	s.last = p.current - 1
+	return stmt
+}
+
 func (p *Parser) classDeclaration() *ClassStmt {
 	name, err := p.consume(token.IDENTIFIER, "Expect class name.")
 	if err != nil {
@@ -58,24 +173,108 @@ func (p *Parser) classDeclaration() *ClassStmt {
 		superclass = NewVariableExpr(p.previous())
 	}
 
-	_, err = p.consume(token.LEFT_BRACE, "Expect '{' before class body.")
+	traits := p.withTraits()
+	methods, fields := p.classBody("class")
+	return &ClassStmt{name: name, methods: methods, fields: fields, superclass: superclass, traits: traits}
+
+}
+
+func (p *Parser) traitDeclaration() *TraitStmt {
+	name, err := p.consume(token.IDENTIFIER, "Expect trait name.")
+	if err != nil {
+		panic(err.Error())
+	}
+
+	traits := p.withTraits()
+	methods, fields := p.classBody("trait")
+	if len(fields) > 0 {
+		panic(fmt.Sprintf("%v A trait cannot declare fields.", fields[0].name))
+	}
+	return &TraitStmt{name: name, methods: methods, traits: traits}
+}
+
+// withTraits parses the optional "with A, B" list of a class or trait.
+func (p *Parser) withTraits() []*Variable {
+	traits := []*Variable{}
+	// with is only a keyword before the body of a class or trait
+	if !p.check(token.IDENTIFIER) || p.peek().Lexeme != "with" {
+		return traits
+	}
+	p.advance()
+
+	for {
+		name, err := p.consume(token.IDENTIFIER, "Expect trait name.")
+		if err != nil {
+			panic(err.Error())
+		}
+		traits = append(traits, NewVariableExpr(name))
+		if !p.match(token.COMMA) {
+			return traits
+		}
+	}
+}
+
+// classBody parses the methods and "var" field declarations between the
+// braces of a class or trait.
+func (p *Parser) classBody(kind string) ([]*FunctionStmt, []*VarStmt) {
+	_, err := p.consume(token.LEFT_BRACE, "Expect '{' before "+kind+" body.")
 	if err != nil {
 		panic(err.Error())
 	}
 
 	methods := []*FunctionStmt{}
+	fields := []*VarStmt{}
 
 	for !p.check(token.RIGHT_BRACE) && !p.isAtEnd() {
-		methods = append(methods, p.function("method"))
+		start := p.current
+		if p.match(token.VAR) {
+			fields = append(fields, p.spanned(p.varDeclaration(), start).(*VarStmt))
+			continue
+		}
+		doc := docComment(p.peek())
+		m := p.method()
+		m.doc = doc
+		p.spanned(m, start)
+		methods = append(methods, m)
+	}
+
+	_, err = p.consume(token.RIGHT_BRACE, "Expect '}' after "+kind+" body.")
+	if err != nil {
+		panic(err.Error())
+	}
+	return methods, fields
+}
+
+// method parses a method of a class body: a plain method, a static one,
+// a getter written without a parameter list or a setter named "name=".
+func (p *Parser) method() *FunctionStmt {
+	// static is only a keyword in front of a method name
+	static := p.check(token.IDENTIFIER) && p.peek().Lexeme == "static" &&
+		p.tokens[p.current+1].Type == token.IDENTIFIER
+	if static {
+		p.advance()
 	}
 
-	_, err = p.consume(token.RIGHT_BRACE, "Expect '}' after class body.")
+	name, err := p.consume(token.IDENTIFIER, "Expect method name.")
 	if err != nil {
 		panic(err.Error())
 	}
 
-	return &ClassStmt{name: name, methods: methods, superclass: superclass}
+	if !static && p.match(token.LEFT_BRACE) {
+		return &FunctionStmt{name: name, params: []*token.Token{}, body: p.block(), getter: true}
+	}
+	if !static && p.match(token.EQUAL) {
+		f := p.functionRest(name, "setter")
+		if len(f.params) != 1 {
+			panic(fmt.Sprintf("%v A setter must have exactly one parameter.", name))
+		}
+		f.setter = true
+		return f
+	}
 
+	f := p.functionRest(name, "method")
+	f.static = static
+	return f
 }
 
 func (p *Parser) function(kind string) *FunctionStmt {
@@ -83,8 +282,13 @@ func (p *Parser) function(kind string) *FunctionStmt {
 	if err != nil {
 		panic(err.Error())
 	}
+	return p.functionRest(name, kind)
+}
 
-	_, err = p.consume(token.LEFT_PAREN, "Expect '(' after "+kind+" name.")
+// functionRest parses the parameters and body of a function whose name
+// has been consumed.
+func (p *Parser) functionRest(name *token.Token, kind string) *FunctionStmt {
+	_, err := p.consume(token.LEFT_PAREN, "Expect '(' after "+kind+" name.")
 	if err != nil {
 		panic(err.Error())
 	}
@@ -127,46 +331,60 @@ func (p *Parser) function(kind string) *FunctionStmt {
 }
 
 func (p *Parser) varDeclaration() Stmt {
+	constant := p.previous().Type == token.CONST
 	name, err := p.consume(token.IDENTIFIER, "Expect variable name.")
 	if err != nil {
 		panic(err.Error())
 	}
 
 	var initializer Expr
-	if p.match(token.EQUAL) {
+	if constant {
+		if _, err := p.consume(token.EQUAL, "Expect '=' after constant name."); err != nil {
+			panic(err.Error())
+		}
+		initializer = p.expression()
+	} else if p.match(token.EQUAL) {
 		initializer = p.expression()
 	}
-	p.consume(token.SEMICOLON, "Expect ';' after variable declaration.")
+	_, err = p.consume(token.SEMICOLON, "Expect ';' after variable declaration.")
 	if err != nil {
 		panic(err.Error())
 	}
-	return &VarStmt{name: name, initializer: initializer}
+	return &VarStmt{name: name, initializer: initializer, constant: constant}
 
 }
 
 func (p *Parser) statement() Stmt {
+	start := p.current
 	if p.match(token.WHILE) {
-		return p.whileStatement()
+		return p.spanned(p.whileStatement(), start)
 	}
 	if p.match(token.FOR) {
-		return p.forStatement()
+		return p.spanned(p.forStatement(), start)
 	}
 	if p.match(token.IF) {
-		return p.ifStatement()
+		return p.spanned(p.ifStatement(), start)
 	}
 	if p.match(token.PRINT) {
-		return p.printStatement()
+		return p.spanned(p.printStatement(), start)
 	}
 	if p.match(token.RETURN) {
-		return p.returnStatement()
+		return p.spanned(p.returnStatement(), start)
+	}
+	if p.match(token.TRY) {
+		return p.spanned(p.tryStatement(), start)
+	}
+	if p.match(token.THROW) {
+		return p.spanned(p.throwStatement(), start)
 	}
 	if p.match(token.LEFT_BRACE) {
-		return &BlockStmt{p.block()}
+		return p.spanned(&BlockStmt{statments: p.block()}, start)
 	}
 
-	return p.expressionStatement()
+	return p.spanned(p.expressionStatement(), start)
 }
 func (p *Parser) whileStatement() Stmt {
+	keyword := p.previous()
 	_, err := p.consume(token.LEFT_PAREN, "Expect '(' after while.")
 	if err != nil {
 		panic(err.Error())
@@ -181,12 +399,15 @@ func (p *Parser) whileStatement() Stmt {
 
 	body := p.statement()
 
-	return &WhileStmt{condition: condition, body: body}
+	return &WhileStmt{keyword: keyword, condition: condition, body: body}
 
 }
 
 func (p *Parser) forStatement() Stmt {
-	p.consume(token.LEFT_PAREN, "Expect '(' after 'for'.")
+	keyword := p.previous()
+	if _, err := p.consume(token.LEFT_PAREN, "Expect '(' after 'for'."); err != nil {
+		panic(err.Error())
+	}
 
 	var initializer Stmt
 	if p.match(token.SEMICOLON) {
@@ -202,30 +423,21 @@ func (p *Parser) forStatement() Stmt {
 		condition = p.expression()
 
 	}
-	p.consume(token.SEMICOLON, "Expect ';' after loop condition.")
+	if _, err := p.consume(token.SEMICOLON, "Expect ';' after loop condition."); err != nil {
+		panic(err.Error())
+	}
 
 	var increment Expr
 	if !p.check(token.RIGHT_PAREN) {
 		increment = p.expression()
 
 	}
-	p.consume(token.RIGHT_PAREN, "Expect ')' after for clauses.")
-	body := p.statement()
-
-	if increment != nil {
-		body = &BlockStmt{statments: []Stmt{body, &ExprStmt{Expr: increment}}}
-	}
-
-	if condition == nil {
-		condition = &Literal{Value: true}
-	}
-	body = &WhileStmt{body: body, condition: condition}
-
-	if initializer != nil {
-		body = &BlockStmt{statments: []Stmt{initializer, body}}
+	if _, err := p.consume(token.RIGHT_PAREN, "Expect ')' after for clauses."); err != nil {
+		panic(err.Error())
 	}
+	body := p.statement()
 
-	return body
+	return &ForStmt{keyword: keyword, initializer: initializer, condition: condition, increment: increment, body: body}
 
 }
 
@@ -268,13 +480,64 @@ func (p *Parser) returnStatement() Stmt {
 	return &ReturnStmt{keyword: keyword, value: value}
 }
 
+func (p *Parser) tryStatement() Stmt {
+	try := &TryStmt{body: p.blockStatement("try")}
+
+	if p.match(token.CATCH) {
+		_, err := p.consume(token.LEFT_PAREN, "Expect '(' after catch.")
+		if err != nil {
+			panic(err.Error())
+		}
+		try.catchName, err = p.consume(token.IDENTIFIER, "Expect name of the caught value.")
+		if err != nil {
+			panic(err.Error())
+		}
+		_, err = p.consume(token.RIGHT_PAREN, "Expect ')' after caught name.")
+		if err != nil {
+			panic(err.Error())
+		}
+		try.catchBody = p.blockStatement("catch")
+	}
+
+	if p.match(token.FINALLY) {
+		try.finallyBody = p.blockStatement("finally")
+	}
+
+	if try.catchBody == nil && try.finallyBody == nil {
+		panic(fmt.Sprintf("%v Expect 'catch' or 'finally' after try block.", p.peek()))
+	}
+	return try
+}
+
+// blockStatement parses the braced block that must follow keyword.
+func (p *Parser) blockStatement(keyword string) *BlockStmt {
+	start := p.current
+	_, err := p.consume(token.LEFT_BRACE, "Expect '{' after "+keyword+".")
+	if err != nil {
+		panic(err.Error())
+	}
+	return p.spanned(&BlockStmt{statments: p.block()}, start).(*BlockStmt)
+}
+
+func (p *Parser) throwStatement() Stmt {
+	keyword := p.previous()
+	value := p.expression()
+
+	_, err := p.consume(token.SEMICOLON, "Expect ';' after thrown value.")
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return &ThrowStmt{keyword: keyword, value: value}
+}
+
 func (p *Parser) printStatement() Stmt {
 	value := p.expression()
 	_, err := p.consume(token.SEMICOLON, "Expect ';' after value.")
 	if err != nil {
 		panic(err.Error())
 	}
-	return &PrintStmt{value}
+	return &PrintStmt{Expr: value}
 }
 
 func (p *Parser) expressionStatement() Stmt {
@@ -283,7 +546,7 @@ func (p *Parser) expressionStatement() Stmt {
 	if err != nil {
 		panic(err.Error())
 	}
-	return &ExprStmt{expr}
+	return &ExprStmt{Expr: expr}
 }
 
 func (p *Parser) expression() Expr {
@@ -302,6 +565,8 @@ func (p *Parser) assignment() Expr {
 			return NewAssignExpr(e.name, value)
 		} else if v, ok := expr.(*Get); ok {
 			return &Set{object: v.object, name: v.name, value: value}
+		} else if v, ok := expr.(*Index); ok {
+			return &IndexSet{object: v.object, bracket: v.bracket, index: v.index, value: value}
 		}
 
 		panic(equals.Lexeme + " invalid assignment target.")
@@ -405,7 +670,7 @@ func (p *Parser) term() Expr {
 
 	for p.match(token.MINUS, token.PLUS) {
 		operator := p.previous()
-		right := p.term()
+		right := p.factor()
 		expr = NewBinaryExpr(expr, operator, right)
 	}
 
@@ -446,6 +711,14 @@ func (p *Parser) call() Expr {
 				panic(err.Error())
 			}
 			expr = &Get{object: expr, name: name}
+		} else if p.match(token.LEFT_BRACKET) {
+			bracket := p.previous()
+			index := p.expression()
+			_, err := p.consume(token.RIGHT_BRACKET, "Expect ']' after index.")
+			if err != nil {
+				panic(err.Error())
+			}
+			expr = &Index{object: expr, bracket: bracket, index: index}
 		} else {
 			break
 		}
@@ -520,12 +793,12 @@ func (p *Parser) primary() Expr {
 		expr := p.expression()
 		_, err := p.consume(token.RIGHT_PAREN, "Expect ')' after expression.")
 		if err != nil {
-			errorhandling.ReportAndExit(0, "", "Expect ')' after expression.")
+			panic(err.Error())
 		}
 		return NewGroupingExpr(expr)
 	}
 
-	panic(fmt.Sprintf("error at line %d: unknown token '%s'", p.peek().Line, p.peek().Literal))
+	panic(fmt.Sprintf("error at line %d: unknown token '%s'", p.peek().Line, p.peek().Lexeme))
 }
 
 func (p *Parser) block() []Stmt {
@@ -35,28 +35,34 @@ func (astp *AstPrinter) VisitUnary(expr *Unary) any {
 	return astp.parenthesize(expr.Operator.Lexeme, expr.Right)
 }
 func (astp *AstPrinter) VisitVariable(expr *Variable) any {
-	return nil
+	return expr.name.Lexeme
 }
 func (astp *AstPrinter) VisitAssign(expr *Assign) any {
-	return nil
+	return astp.parenthesize("= "+expr.name.Lexeme, expr.value)
 }
 func (astp *AstPrinter) VisitLogical(expr *Logical) any {
-	return nil
+	return astp.parenthesize(expr.operator.Lexeme, expr.left, expr.right)
 }
 func (astp *AstPrinter) VisitCall(expr *CallExpr) any {
-	return nil
+	return astp.parenthesize("call", append([]Expr{expr.callee}, expr.arguments...)...)
 }
 func (astp *AstPrinter) VisitGet(expr *Get) any {
-	return nil
+	return astp.parenthesize(". "+expr.name.Lexeme, expr.object)
 }
 func (astp *AstPrinter) VisitSet(expr *Set) any {
-	return nil
+	return astp.parenthesize("= ."+expr.name.Lexeme, expr.object, expr.value)
+}
+func (astp *AstPrinter) VisitIndex(expr *Index) any {
+	return astp.parenthesize("[]", expr.object, expr.index)
+}
+func (astp *AstPrinter) VisitIndexSet(expr *IndexSet) any {
+	return astp.parenthesize("= []", expr.object, expr.index, expr.value)
 }
 func (astp *AstPrinter) VisitThis(expr *This) any {
-	return nil
+	return "this"
 }
 func (astp *AstPrinter) VisitSuper(expr *Super) any {
-	return nil
+	return "(super " + expr.method.Lexeme + ")"
 }
 
 func (astp *AstPrinter) parenthesize(name string, exprs ...Expr) string {
@@ -75,3 +81,137 @@ func (astp *AstPrinter) parenthesize(name string, exprs ...Expr) string {
 	ss.WriteString(")")
 	return ss.String()
 }
+
+// PrintStmts renders a program as one s-expression per statement, with
+// nested statements indented below the one that contains them.
+func (astp *AstPrinter) PrintStmts(stmts []Stmt) string {
+	var ss strings.Builder
+	for _, s := range stmts {
+		ss.WriteString(astp.stmt(s, 0))
+		ss.WriteString("\n")
+	}
+	return ss.String()
+}
+
+func (astp *AstPrinter) stmt(s Stmt, depth int) string {
+	indent := strings.Repeat("  ", depth)
+	nested := func(name string, parts []string, body ...Stmt) string {
+		var ss strings.Builder
+		ss.WriteString(indent + "(" + strings.Join(append([]string{name}, parts...), " "))
+		for _, b := range body {
+			ss.WriteString("\n")
+			ss.WriteString(astp.stmt(b, depth+1))
+		}
+		ss.WriteString(")")
+		return ss.String()
+	}
+	optional := func(e Expr) string {
+		if e == nil {
+			return "nil"
+		}
+		return astp.Print(e)
+	}
+
+	switch st := s.(type) {
+	case *PrintStmt:
+		return indent + astp.parenthesize("print", st.Expr)
+	case *ExprStmt:
+		return indent + astp.parenthesize(";", st.Expr)
+	case *VarStmt:
+		keyword := "var"
+		if st.constant {
+			keyword = "const"
+		}
+		return indent + "(" + keyword + " " + st.name.Lexeme + " " + optional(st.initializer) + ")"
+	case *ReturnStmt:
+		return indent + "(return " + optional(st.value) + ")"
+	case *BlockStmt:
+		return nested("block", nil, st.statments...)
+	case *IfStmt:
+		if st.elseBranch == nil {
+			return nested("if", []string{astp.Print(st.condition)}, st.thenBranch)
+		}
+		return nested("if-else", []string{astp.Print(st.condition)}, st.thenBranch, st.elseBranch)
+	case *WhileStmt:
+		return nested("while", []string{astp.Print(st.condition)}, st.body)
+	case *ForStmt:
+		init := "nil"
+		if st.initializer != nil {
+			init = strings.TrimSpace(astp.stmt(st.initializer, 0))
+		}
+		return nested("for", []string{init, optional(st.condition), optional(st.increment)}, st.body)
+	case *FunctionStmt:
+		kind := "fun"
+		switch {
+		case st.static:
+			kind = "static"
+		case st.getter:
+			kind = "get"
+		case st.setter:
+			kind = "set"
+		}
+		return nested(kind, astp.params(st), st.body...)
+	case *ClassStmt:
+		parts := []string{st.name.Lexeme}
+		if st.superclass != nil {
+			parts = append(parts, "<", st.superclass.name.Lexeme)
+		}
+		if len(st.traits) > 0 {
+			parts = append(append(parts, "with"), traitNames(st.traits)...)
+		}
+		if st.strict {
+			parts = append([]string{"strict"}, parts...)
+		}
+		body := []Stmt{}
+		for _, field := range st.fields {
+			body = append(body, field)
+		}
+		return nested("class", parts, append(body, astp.methods(st.methods)...)...)
+	case *TraitStmt:
+		parts := []string{st.name.Lexeme}
+		if len(st.traits) > 0 {
+			parts = append(append(parts, "with"), traitNames(st.traits)...)
+		}
+		return nested("trait", parts, astp.methods(st.methods)...)
+	case *ImportStmt:
+		parts := []string{st.path.Lexeme}
+		for _, name := range st.names {
+			parts = append(parts, name.Lexeme)
+		}
+		return indent + "(import " + strings.Join(parts, " ") + ")"
+	case *TryStmt:
+		body := []Stmt{st.body}
+		parts := []string{}
+		if st.catchBody != nil {
+			body = append(body, st.catchBody)
+			parts = append(parts, st.catchName.Lexeme)
+		}
+		if st.finallyBody != nil {
+			body = append(body, st.finallyBody)
+			parts = append(parts, "finally")
+		}
+		return nested("try", parts, body...)
+	case *ThrowStmt:
+		return indent + astp.parenthesize("throw", st.value)
+	case *ExportStmt:
+		return nested("export", nil, st.declaration)
+	}
+
+	return indent + fmt.Sprintf("%v", s)
+}
+
+func (astp *AstPrinter) methods(methods []*FunctionStmt) []Stmt {
+	stmts := make([]Stmt, len(methods))
+	for i, m := range methods {
+		stmts[i] = m
+	}
+	return stmts
+}
+
+func (astp *AstPrinter) params(f *FunctionStmt) []string {
+	params := make([]string, len(f.params))
+	for i, p := range f.params {
+		params[i] = p.Lexeme
+	}
+	return []string{f.name.Lexeme, "(" + strings.Join(params, " ") + ")"}
+}
@@ -18,6 +18,19 @@ type Resolver struct {
 	*scopes
 	currentFunction functionType
 	currentClass    classType
+	// private are the private names of the class or trait being resolved
+	private *privateNames
+	// constants declared so far, globals under a nil scope
+	constants map[binding]bool
+	// known are the globals of the program, found before resolving it
+	known        map[string]bool
+	checkGlobals bool
+	warnings     []string
+}
+
+type binding struct {
+	scope *scope
+	name  string
 }
 
 func NewResolver(interpreter *Interpreter) *Resolver {
@@ -26,6 +39,8 @@ func NewResolver(interpreter *Interpreter) *Resolver {
 		scopes:          &scopes{},
 		currentFunction: none,
 		currentClass:    NONE,
+		constants:       map[binding]bool{},
+		checkGlobals:    true,
 	}
 }
 
@@ -44,19 +59,42 @@ func (s *scopes) pop() {
 	*s = (*s)[:len(*s)-1]
 }
 
-func (r *Resolver) resolveLocal(expr Expr, name *token.Token) {
+// resolveLocal resolves name to the scope declaring it, reporting false
+// when no scope does and it must be a global.
+func (r *Resolver) resolveLocal(expr Expr, name *token.Token) bool {
 	for i := len(*r.scopes) - 1; i >= 0; i-- {
 		s := (*r.scopes)[i]
 		if _, defined := (*s)[name.Lexeme]; defined {
 			depth := len(*r.scopes) - 1 - i
 			r.Interpreter.Resolve(expr, depth)
-			// s.use(name.Lexeme)
-			return
+			return true
+		}
+	}
+	return false
+}
+
+// binding returns where name resolves to from the innermost scope.
+func (r *Resolver) binding(name string) binding {
+	for i := len(*r.scopes) - 1; i >= 0; i-- {
+		s := (*r.scopes)[i]
+		if _, defined := (*s)[name]; defined {
+			return binding{s, name}
 		}
 	}
+	return binding{nil, name}
+}
+
+func (r *Resolver) innermost() *scope {
+	if len(*r.scopes) == 0 {
+		return nil
+	}
+	return r.peek()
 }
 
 func (r *Resolver) ResolveStmts(stmts []Stmt) {
+	if r.known == nil {
+		r.collectGlobals(stmts)
+	}
 	for _, s := range stmts {
 		r.resolveStmt(s)
 	}
@@ -79,6 +117,12 @@ func (r *Resolver) endScope() {
 }
 
 func (r *Resolver) declare(name *token.Token) {
+	if isPrivate(name.Lexeme) {
+		panic("'" + name.Lexeme + "' is private; only fields and methods can have private names.")
+	}
+	if r.constants[binding{r.innermost(), name.Lexeme}] {
+		panic("cannot redeclare constant '" + name.Lexeme + "'.")
+	}
 	if len(*r.scopes) == 0 {
 		return
 	}
@@ -114,30 +158,43 @@ func (r *Resolver) visitVariableStmt(vs *VarStmt) any {
 		r.resolveExpr(vs.initializer)
 	}
 	r.define(vs.name)
+	if vs.constant {
+		r.constants[binding{r.innermost(), vs.name.Lexeme}] = true
+	}
 	return nil
 }
 
 func (r *Resolver) VisitVariable(expr *Variable) any {
+	if isPrivate(expr.name.Lexeme) {
+		panic("'" + expr.name.Lexeme + "' is private; use 'this." + expr.name.Lexeme + "'.")
+	}
 
 	if len(*r.scopes) > 0 {
-		if declared, defined := (*r.scopes.peek())[expr.name.Lexeme]; declared && !defined {
+		if defined, declared := (*r.scopes.peek())[expr.name.Lexeme]; declared && !defined {
 			panic("cannot read local variable in its own initializer")
 		}
 	}
 
-	r.resolveLocal(expr, expr.name)
+	if !r.resolveLocal(expr, expr.name) {
+		r.checkGlobal(expr.name)
+	}
 	return nil
 }
 
 func (r *Resolver) VisitAssign(expr *Assign) any {
+	if r.constants[r.binding(expr.name.Lexeme)] {
+		panic("cannot assign to constant '" + expr.name.Lexeme + "'.")
+	}
 	r.resolveExpr(expr.value)
-	r.resolveLocal(expr, expr.name)
+	if !r.resolveLocal(expr, expr.name) {
+		r.checkGlobal(expr.name)
+	}
 	return nil
 }
 
 func (r *Resolver) visitFunctionStmt(fs *FunctionStmt) any {
 	r.declare(fs.name)
-	// r.define(fs.name)
+	r.define(fs.name)
 	r.resolveFunction(fs, function)
 	return nil
 }
@@ -196,6 +253,22 @@ func (r *Resolver) visitWhileStmt(stmt *WhileStmt) any {
 	return nil
 }
 
+func (r *Resolver) visitForStmt(stmt *ForStmt) any {
+	r.beginScope()
+	if stmt.initializer != nil {
+		r.resolveStmt(stmt.initializer)
+	}
+	if stmt.condition != nil {
+		r.resolveExpr(stmt.condition)
+	}
+	if stmt.increment != nil {
+		r.resolveExpr(stmt.increment)
+	}
+	r.resolveStmt(stmt.body)
+	r.endScope()
+	return nil
+}
+
 func (r *Resolver) VisitBinary(expr *Binary) any {
 	r.resolveExpr(expr.Left)
 	r.resolveExpr(expr.Right)
@@ -232,8 +305,9 @@ func (r *Resolver) VisitUnary(expr *Unary) any {
 }
 
 func (r *Resolver) visitClassStmt(stmt *ClassStmt) any {
-	cc := r.currentClass
+	cc, private := r.currentClass, r.private
 	r.currentClass = CLASS
+	r.private = newPrivateNames(stmt.name.Lexeme, stmt.fields, stmt.methods)
 
 	r.declare(stmt.name)
 	if stmt.superclass != nil {
@@ -242,6 +316,10 @@ func (r *Resolver) visitClassStmt(stmt *ClassStmt) any {
 	}
 	r.define(stmt.name)
 
+	for _, t := range stmt.traits {
+		r.resolveExpr(t)
+	}
+
 	if stmt.superclass != nil && stmt.name.Lexeme == stmt.superclass.name.Lexeme {
 		panic("A class cannot inherit from itself")
 	}
@@ -256,9 +334,20 @@ func (r *Resolver) visitClassStmt(stmt *ClassStmt) any {
 	r.beginScope()
 	(*r.scopes.peek())["this"] = true
 
+	declared := map[string]bool{}
+	for _, f := range stmt.fields {
+		if declared[f.name.Lexeme] {
+			panic("field '" + f.name.Lexeme + "' is declared twice in " + stmt.name.Lexeme + ".")
+		}
+		declared[f.name.Lexeme] = true
+		if f.initializer != nil {
+			r.resolveExpr(f.initializer)
+		}
+	}
+
 	for _, m := range stmt.methods {
 
-		if m.name.Lexeme == "this" {
+		if m.name.Lexeme == "init" {
 			r.resolveFunction(m, initializer)
 		} else {
 
@@ -273,22 +362,54 @@ func (r *Resolver) visitClassStmt(stmt *ClassStmt) any {
 		r.endScope()
 	}
 
-	r.currentClass = cc
+	r.currentClass, r.private = cc, private
 
 	return nil
 }
 
 func (r *Resolver) VisitGet(expr *Get) any {
+	r.checkPrivate(expr.object, expr.name)
 	r.resolveExpr(expr.object)
 	return nil
 }
 
 func (r *Resolver) VisitSet(expr *Set) any {
+	r.checkPrivate(expr.object, expr.name)
 	r.resolveExpr(expr.value)
 	r.resolveExpr(expr.object)
 	return nil
 }
 
+// checkPrivate makes sure a private property is only used through this
+// inside a class.
+func (r *Resolver) checkPrivate(object Expr, name *token.Token) {
+	if !isPrivate(name.Lexeme) {
+		return
+	}
+	if r.currentClass == NONE {
+		panic("cannot use private property '" + name.Lexeme + "' outside of a class.")
+	}
+	if _, ok := object.(*This); !ok {
+		panic("private property '" + name.Lexeme + "' can only be used through 'this'.")
+	}
+	if !r.private.names[name.Lexeme] {
+		panic("private property '" + name.Lexeme + "' is not declared in " + r.private.owner + ".")
+	}
+}
+
+func (r *Resolver) VisitIndex(expr *Index) any {
+	r.resolveExpr(expr.object)
+	r.resolveExpr(expr.index)
+	return nil
+}
+
+func (r *Resolver) VisitIndexSet(expr *IndexSet) any {
+	r.resolveExpr(expr.value)
+	r.resolveExpr(expr.object)
+	r.resolveExpr(expr.index)
+	return nil
+}
+
 func (r *Resolver) VisitThis(expr *This) any {
 	if r.currentClass == NONE {
 		panic("Cannot use 'this' outside of a class.")
@@ -300,9 +421,74 @@ func (r *Resolver) VisitThis(expr *This) any {
 func (r *Resolver) VisitSuper(expr *Super) any {
 	if r.currentClass == NONE {
 		panic("cannot use 'super' outside of a class")
+	} else if r.currentClass == TRAIT {
+		panic("cannot use 'super' in a trait")
 	} else if r.currentClass != SUBCLASS {
 		panic("cannot use 'super' in a class with no superclass")
 	}
 	r.resolveLocal(expr, expr.keyword)
 	return nil
 }
+
+func (r *Resolver) visitTraitStmt(stmt *TraitStmt) any {
+	cc, private := r.currentClass, r.private
+	r.currentClass = TRAIT
+	r.private = newPrivateNames(stmt.name.Lexeme, nil, stmt.methods)
+
+	r.declare(stmt.name)
+	for _, t := range stmt.traits {
+		if t.name.Lexeme == stmt.name.Lexeme {
+			panic("A trait cannot be made of itself")
+		}
+		r.resolveExpr(t)
+	}
+	r.define(stmt.name)
+
+	r.beginScope()
+	(*r.scopes.peek())["this"] = true
+	for _, m := range stmt.methods {
+		if m.name.Lexeme == "init" && !m.static {
+			panic("a trait cannot have an initializer.")
+		}
+		r.resolveFunction(m, method)
+	}
+	r.endScope()
+
+	r.currentClass, r.private = cc, private
+	return nil
+}
+
+func (r *Resolver) visitTryStmt(stmt *TryStmt) any {
+	r.resolveStmt(stmt.body)
+	if stmt.catchBody != nil {
+		r.beginScope()
+		r.declare(stmt.catchName)
+		r.define(stmt.catchName)
+		r.ResolveStmts(stmt.catchBody.statments)
+		r.endScope()
+	}
+	if stmt.finallyBody != nil {
+		r.resolveStmt(stmt.finallyBody)
+	}
+	return nil
+}
+
+func (r *Resolver) visitThrowStmt(stmt *ThrowStmt) any {
+	r.resolveExpr(stmt.value)
+	return nil
+}
+
+func (r *Resolver) visitImportStmt(stmt *ImportStmt) any {
+	if len(*r.scopes) > 0 {
+		panic("cannot import outside of the top level.")
+	}
+	return nil
+}
+
+func (r *Resolver) visitExportStmt(stmt *ExportStmt) any {
+	if len(*r.scopes) > 0 {
+		panic("cannot export outside of the top level.")
+	}
+	r.resolveStmt(stmt.declaration)
+	return nil
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	expectOutput = regexp.MustCompile(`// expect: (.*)$`)
+	expectError  = regexp.MustCompile(`// expect runtime error: (.*)$`)
+)
+
+// TestScripts runs every script in testdata and checks that it prints what
+// its "// expect: value" comments say, in order. A script may end with a
+// "// expect runtime error: message" comment on the line that fails.
+func TestScripts(t *testing.T) {
+	scripts, err := filepath.Glob("testdata/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range scripts {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{}
+		expectedError := ""
+		for _, line := range strings.Split(string(source), "\n") {
+			if m := expectOutput.FindStringSubmatch(line); m != nil {
+				expected = append(expected, m[1])
+			}
+			if m := expectError.FindStringSubmatch(line); m != nil {
+				expectedError = m[1]
+			}
+		}
+
+		for _, optimize := range []bool{false, true} {
+			out, err := run(string(source), optimize)
+
+			got := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+			if out == "" {
+				got = []string{}
+			}
+			if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+				t.Fatalf("%s - output wrong, expected:\n%s\ngot:\n%s", path, strings.Join(expected, "\n"), out)
+			}
+
+			message := ""
+			if err != nil {
+				message, _, _ = strings.Cut(err.Error(), "\n")
+			}
+			if message != expectedError {
+				t.Fatalf("%s - expected error %q, got %q", path, expectedError, message)
+			}
+		}
+	}
+}
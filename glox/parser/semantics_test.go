@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/scanner"
+)
+
+func interpretSource(source string) (out string, err error) {
+	var b bytes.Buffer
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = b.String(), fmt.Errorf("%v", r)
+		}
+	}()
+
+	s := scanner.NewScanner(source)
+	s.ScanTokens()
+	stmts := NewParser(s.GetTokens()).Parse()
+	i := NewInterpreter(stmts, WithOutput(&b))
+	NewResolver(i).ResolveStmts(stmts)
+	i.Interpret(stmts)
+	return b.String(), nil
+}
+
+func TestSemantics(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"init is the initializer", `
+class Point {
+  init(x) { this.x = x; }
+}
+print Point(3).x;`, "3\n"},
+		{"init returns this, even on an early return", `
+class A {
+  init() { this.n = 1; return; this.n = 2; }
+}
+var a = A();
+print a.init() == a;
+print a.n;`, "true\n1\n"},
+		{"+ and - are left associative", `
+print 10 - 3 - 2;
+print 2 * 6 / 3;`, "5\n4\n"},
+		{"comparisons bind looser than + and -", `
+print 1 < 2 + 3;
+print 4 > 1 - 2 * 2;`, "true\ntrue\n"},
+		{"assignment sets only the resolved variable", `
+var a = "global";
+{
+  fun set() { a = "set"; }
+  var a = "local";
+  set();
+  print a;
+}
+print a;`, "local\nset\n"},
+		{"each instance has its own fields", `
+class A {}
+var x = A();
+var y = A();
+x.f = 1;
+y.f = 2;
+print x.f;`, "1\n"},
+		{"methods are found on any superclass", `
+class A { hello() { return "hello"; } }
+class B < A {}
+class C < B {}
+print C().hello();`, "hello\n"},
+		{"! works on any value", `
+print !nil;
+print !0;
+print !"";`, "true\nfalse\nfalse\n"},
+	}
+
+	for _, tt := range tests {
+		out, err := interpretSource(tt.source)
+		if err != nil {
+			t.Fatalf("%s - unexpected error: %v", tt.name, err)
+		}
+		if out != tt.expected {
+			t.Fatalf("%s - output wrong, expected:\n%s\ngot:\n%s", tt.name, tt.expected, out)
+		}
+	}
+}
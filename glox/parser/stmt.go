@@ -4,6 +4,18 @@ import "github.com/Martin-Martinez4/crafting-interpreters/glox/token"
 
 type Stmt interface {
 	Accept(StmtVisitor) any
+	span() *stmtSpan
+}
+
+// stmtSpan records the indexes of the first and last token of a statement
+// so tools like the formatter can find the comments written around it.
+type stmtSpan struct {
+	first int
+	last  int
+}
+
+func (s *stmtSpan) span() *stmtSpan {
+	return s
 }
 
 type StmtVisitor interface {
@@ -13,13 +25,20 @@ type StmtVisitor interface {
 	visitBlockStmt(*BlockStmt) any
 	visitIfStmt(*IfStmt) any
 	visitWhileStmt(*WhileStmt) any
+	visitForStmt(*ForStmt) any
 	visitFunctionStmt(*FunctionStmt) any
 	visitReturnStmt(*ReturnStmt) any
 	visitClassStmt(*ClassStmt) any
+	visitImportStmt(*ImportStmt) any
+	visitExportStmt(*ExportStmt) any
+	visitTryStmt(*TryStmt) any
+	visitThrowStmt(*ThrowStmt) any
+	visitTraitStmt(*TraitStmt) any
 }
 
 type PrintStmt struct {
 	Expr
+	stmtSpan
 }
 
 func (p *PrintStmt) Accept(v StmtVisitor) any {
@@ -28,6 +47,7 @@ func (p *PrintStmt) Accept(v StmtVisitor) any {
 
 type ExprStmt struct {
 	Expr
+	stmtSpan
 }
 
 func (e *ExprStmt) Accept(v StmtVisitor) any {
@@ -37,6 +57,8 @@ func (e *ExprStmt) Accept(v StmtVisitor) any {
 type VarStmt struct {
 	name        *token.Token
 	initializer Expr
+	constant    bool
+	stmtSpan
 }
 
 func (vs *VarStmt) Accept(v StmtVisitor) any {
@@ -45,6 +67,7 @@ func (vs *VarStmt) Accept(v StmtVisitor) any {
 
 type BlockStmt struct {
 	statments []Stmt
+	stmtSpan
 }
 
 func (vb *BlockStmt) Accept(v StmtVisitor) any {
@@ -55,6 +78,7 @@ type IfStmt struct {
 	condition  Expr
 	thenBranch Stmt
 	elseBranch Stmt
+	stmtSpan
 }
 
 func (i *IfStmt) Accept(v StmtVisitor) any {
@@ -62,18 +86,40 @@ func (i *IfStmt) Accept(v StmtVisitor) any {
 }
 
 type WhileStmt struct {
+	keyword   *token.Token
 	condition Expr
 	body      Stmt
+	stmtSpan
 }
 
 func (w *WhileStmt) Accept(v StmtVisitor) any {
 	return v.visitWhileStmt(w)
 }
 
+type ForStmt struct {
+	keyword     *token.Token
+	initializer Stmt
+	condition   Expr
+	increment   Expr
+	body        Stmt
+	stmtSpan
+}
+
+func (f *ForStmt) Accept(v StmtVisitor) any {
+	return v.visitForStmt(f)
+}
+
 type FunctionStmt struct {
 	name   *token.Token
 	params []*token.Token
 	body   []Stmt
+	doc    string
+	// static methods belong to the class, getters and setters run when
+	// a property of that name is read or assigned
+	static bool
+	getter bool
+	setter bool
+	stmtSpan
 }
 
 func (f *FunctionStmt) Accept(v StmtVisitor) any {
@@ -83,19 +129,88 @@ func (f *FunctionStmt) Accept(v StmtVisitor) any {
 type ReturnStmt struct {
 	keyword *token.Token
 	value   Expr
+	stmtSpan
 }
 
 func (r *ReturnStmt) Accept(v StmtVisitor) any {
-	v.visitReturnStmt(r)
-	return nil
+	return v.visitReturnStmt(r)
 }
 
 type ClassStmt struct {
 	name       *token.Token
 	methods    []*FunctionStmt
+	fields     []*VarStmt
 	superclass *Variable
+	traits     []*Variable
+	doc        string
+	// strict classes only have the fields they declare
+	strict bool
+	stmtSpan
 }
 
 func (c *ClassStmt) Accept(v StmtVisitor) any {
 	return v.visitClassStmt(c)
 }
+
+// TraitStmt declares methods for classes to take in with "with". A trait
+// may itself be made of other traits.
+type TraitStmt struct {
+	name    *token.Token
+	methods []*FunctionStmt
+	traits  []*Variable
+	doc     string
+	stmtSpan
+}
+
+func (t *TraitStmt) Accept(v StmtVisitor) any {
+	return v.visitTraitStmt(t)
+}
+
+// ImportStmt binds the names exported by the module at path, or only the
+// listed names when there are any.
+type ImportStmt struct {
+	keyword *token.Token
+	path    *token.Token
+	names   []*token.Token
+	stmtSpan
+}
+
+func (i *ImportStmt) Accept(v StmtVisitor) any {
+	return v.visitImportStmt(i)
+}
+
+// ExportStmt is a top level function, variable or class declaration that
+// other modules may import.
+type ExportStmt struct {
+	declaration Stmt
+	stmtSpan
+}
+
+func (e *ExportStmt) Accept(v StmtVisitor) any {
+	return v.visitExportStmt(e)
+}
+
+// TryStmt runs body, then catchBody with the thrown value bound to
+// catchName if body throws, then finallyBody however the others ended.
+// Either catchBody or finallyBody may be nil, not both.
+type TryStmt struct {
+	body        *BlockStmt
+	catchName   *token.Token
+	catchBody   *BlockStmt
+	finallyBody *BlockStmt
+	stmtSpan
+}
+
+func (t *TryStmt) Accept(v StmtVisitor) any {
+	return v.visitTryStmt(t)
+}
+
+type ThrowStmt struct {
+	keyword *token.Token
+	value   Expr
+	stmtSpan
+}
+
+func (t *ThrowStmt) Accept(v StmtVisitor) any {
+	return v.visitThrowStmt(t)
+}
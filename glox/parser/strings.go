@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// The string natives count positions in characters, not bytes.
+
+func init() {
+	registerCore(&native{name: "len", params: 1, doc: "Returns the number of characters in a string or elements in a list.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			switch v := arguments[0].(type) {
+			case string:
+				return float64(utf8.RuneCountInString(v))
+			case *List:
+				return float64(len(v.elements))
+			}
+			panic("len: argument 1 must be a string or a list.")
+		}})
+
+	registerCore(&native{name: "substr", params: 3, doc: "Returns the characters of a string from start up to, not including, end.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			s := []rune(stringArg("substr", arguments, 0))
+			start, end := intArg("substr", arguments, 1), intArg("substr", arguments, 2)
+			if start < 0 || end > len(s) || start > end {
+				panic(fmt.Sprintf("substr: range %d to %d out of bounds for a string of length %d.", start, end, len(s)))
+			}
+			interpreter.allocate()
+			return string(s[start:end])
+		}})
+
+	registerCore(&native{name: "indexOf", params: 2, doc: "Returns the position of the first occurrence of a substring, or -1.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			s, sub := stringArg("indexOf", arguments, 0), stringArg("indexOf", arguments, 1)
+			i := strings.Index(s, sub)
+			if i < 0 {
+				return float64(-1)
+			}
+			return float64(utf8.RuneCountInString(s[:i]))
+		}})
+
+	registerCore(&native{name: "split", params: 2, doc: "Returns a list of the parts of a string between separators.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			parts := strings.Split(stringArg("split", arguments, 0), stringArg("split", arguments, 1))
+			elements := make([]any, len(parts))
+			for i, part := range parts {
+				interpreter.allocate()
+				elements[i] = part
+			}
+			interpreter.allocate()
+			return NewList(elements)
+		}})
+
+	registerCore(&native{name: "join", params: 2, doc: "Returns the strings of a list joined by a separator.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			l := listArg("join", arguments, 0)
+			sep := stringArg("join", arguments, 1)
+			parts := make([]string, len(l.elements))
+			for i, e := range l.elements {
+				s, ok := e.(string)
+				if !ok {
+					panic(fmt.Sprintf("join: element %d is not a string.", i))
+				}
+				parts[i] = s
+			}
+			interpreter.allocate()
+			return strings.Join(parts, sep)
+		}})
+
+	registerCore(stringFunction("upper", "Returns a string in upper case.", strings.ToUpper))
+	registerCore(stringFunction("lower", "Returns a string in lower case.", strings.ToLower))
+	registerCore(stringFunction("trim", "Returns a string without leading and trailing white space.", strings.TrimSpace))
+
+	registerCore(&native{name: "replace", params: 3, doc: "Returns a string with every occurrence of old replaced by new.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			interpreter.allocate()
+			return strings.ReplaceAll(stringArg("replace", arguments, 0), stringArg("replace", arguments, 1), stringArg("replace", arguments, 2))
+		}})
+
+	registerCore(&native{name: "startsWith", params: 2, doc: "Returns whether a string starts with a prefix.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			return strings.HasPrefix(stringArg("startsWith", arguments, 0), stringArg("startsWith", arguments, 1))
+		}})
+
+	registerCore(&native{name: "endsWith", params: 2, doc: "Returns whether a string ends with a suffix.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			return strings.HasSuffix(stringArg("endsWith", arguments, 0), stringArg("endsWith", arguments, 1))
+		}})
+
+	registerCore(&native{name: "charAt", params: 2, doc: "Returns the character at a position of a string.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			s := []rune(stringArg("charAt", arguments, 0))
+			i := intArg("charAt", arguments, 1)
+			if i < 0 || i >= len(s) {
+				panic(fmt.Sprintf("charAt: index %d out of bounds for a string of length %d.", i, len(s)))
+			}
+			interpreter.allocate()
+			return string(s[i])
+		}})
+
+	registerCore(&native{name: "ord", params: 1, doc: "Returns the code point of a one character string.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			s := []rune(stringArg("ord", arguments, 0))
+			if len(s) != 1 {
+				panic("ord: argument 1 must be a single character.")
+			}
+			return float64(s[0])
+		}})
+
+	registerCore(&native{name: "chr", params: 1, doc: "Returns the one character string for a code point.",
+		fn: func(interpreter *Interpreter, arguments []any) any {
+			c := intArg("chr", arguments, 0)
+			if c < 0 || c > utf8.MaxRune {
+				panic(fmt.Sprintf("chr: %d is not a code point.", c))
+			}
+			interpreter.allocate()
+			return string(rune(c))
+		}})
+}
+
+// stringFunction makes a native that maps one string to another.
+func stringFunction(name string, doc string, f func(string) string) *native {
+	return &native{name: name, params: 1, doc: doc, fn: func(interpreter *Interpreter, arguments []any) any {
+		interpreter.allocate()
+		return f(stringArg(name, arguments, 0))
+	}}
+}
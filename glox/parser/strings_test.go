@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStringNativeErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{`len(1);`, "len: argument 1 must be a string or a list."},
+		{`upper(nil);`, "upper: argument 1 must be a string."},
+		{`substr("abc", 0.5, 1);`, "substr: argument 2 must be a whole number."},
+		{`substr("abc", 2, 1);`, "substr: range 2 to 1 out of bounds for a string of length 3."},
+		{`join(split("a b", " "), 1);`, "join: argument 2 must be a string."},
+		{`join("a b", " ");`, "join: argument 1 must be a list."},
+		{`charAt("abc", 3);`, "charAt: index 3 out of bounds for a string of length 3."},
+		{`ord("ab");`, "ord: argument 1 must be a single character."},
+		{`chr(-1);`, "chr: -1 is not a code point."},
+		{`trim();`, "Expected 1 arguments but got 0."},
+	}
+
+	for _, tt := range tests {
+		_, err := run(tt.source, false)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err+"\n") {
+			t.Fatalf("%s - expected error %q, got %v", tt.source, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Trait is a set of methods, getters, setters and static methods that
+// classes take in with "with".
+//
+// The members of a trait are copied into every class declared with it, so
+// looking up a property of an instance goes:
+//
+//  1. the fields of the instance
+//  2. the members the class declares itself
+//  3. the members of the class's traits
+//  4. the members of the superclass, found the same way
+//
+// Two traits of a class may not both provide a member of the same kind and
+// name unless the class declares it too; that is reported when the class
+// is created. A trait made of other traits is flattened the same way.
+type Trait struct {
+	name string
+	members
+	// traits are the ones the trait is made of, flattened
+	traits []*Trait
+	doc    string
+}
+
+func (t *Trait) String() string {
+	return t.name
+}
+
+// traits evaluates the traits named after "with".
+func (i *Interpreter) traits(names []*Variable) []*Trait {
+	traits := []*Trait{}
+	for _, name := range names {
+		t, ok := name.Accept(i).(*Trait)
+		if !ok {
+			panic(name.name.Lexeme + " is not a trait.")
+		}
+		traits = append(traits, t)
+	}
+	return traits
+}
+
+// compose copies the members of traits into target, the members of the
+// class or trait called name, and returns every trait it is made of.
+func compose(name string, target *members, traits []*Trait) []*Trait {
+	own := make([]map[string]bool, len(target.tables()))
+	for n, table := range target.tables() {
+		own[n] = map[string]bool{}
+		for member := range table {
+			own[n][member] = true
+		}
+	}
+
+	from := map[*Function]*Trait{}
+	all := []*Trait{}
+	for _, t := range traits {
+		all = append(append(all, t), t.traits...)
+
+		for n, table := range t.tables() {
+			into := target.tables()[n]
+
+			names := make([]string, 0, len(table))
+			for member := range table {
+				names = append(names, member)
+			}
+			sort.Strings(names)
+
+			for _, member := range names {
+				f := table[member]
+				if own[n][member] {
+					continue
+				}
+				// the same method reached through two traits is no conflict
+				if existing, ok := into[member]; ok && existing != f {
+					panic(fmt.Sprintf("%s gets '%s' from both %s and %s; declare it in %s to choose.",
+						name, member, from[existing], t, name))
+				}
+				into[member] = f
+				from[f] = t
+			}
+		}
+	}
+	return all
+}
+
+func (i *Interpreter) visitTraitStmt(s *TraitStmt) any {
+	traits := i.traits(s.traits)
+
+	trait := &Trait{name: s.name.Lexeme, members: newMembers(map[string]*Function{}), doc: s.doc}
+	for _, m := range s.methods {
+		trait.add(m, i.environment)
+	}
+	trait.traits = compose(s.name.Lexeme, &trait.members, traits)
+
+	i.environment.define(s.name.Lexeme, trait)
+	return nil
+}
+
+// hasTrait reports whether c or one of its superclasses was declared with
+// trait.
+func (c *Class) hasTrait(trait *Trait) bool {
+	for ; c != nil; c = c.superclass {
+		for _, t := range c.traits {
+			if t == trait {
+				return true
+			}
+		}
+	}
+	return false
+}
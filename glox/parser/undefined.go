@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
+)
+
+// collectGlobals records the names a program can use as globals: those
+// already defined, natives included, and those its top level declares or
+// imports. An import of a whole module brings in what the module's source
+// exports; when that cannot be read the check is left off, with a warning
+// saying why.
+func (r *Resolver) collectGlobals(stmts []Stmt) {
+	r.known = map[string]bool{}
+	for name := range r.Interpreter.globals.values {
+		r.known[name] = true
+	}
+
+	for _, s := range stmts {
+		if e, ok := s.(*ExportStmt); ok {
+			s = e.declaration
+		}
+		switch d := s.(type) {
+		case *VarStmt:
+			r.known[d.name.Lexeme] = true
+		case *FunctionStmt:
+			r.known[d.name.Lexeme] = true
+		case *ClassStmt:
+			r.known[d.name.Lexeme] = true
+		case *TraitStmt:
+			r.known[d.name.Lexeme] = true
+		case *ImportStmt:
+			for _, name := range d.names {
+				r.known[name.Lexeme] = true
+			}
+			if len(d.names) == 0 {
+				r.importAll(d)
+			}
+		}
+	}
+}
+
+func (r *Resolver) importAll(s *ImportStmt) {
+	path := s.path.Literal.(string)
+	names, err := r.Interpreter.moduleExports(path)
+	if err != nil {
+		r.checkGlobals = false
+		r.warnings = append(r.warnings, fmt.Sprintf("[line %d] cannot check the names used from module '%s': %v", s.keyword.Line, path, err))
+		return
+	}
+	for _, name := range names {
+		r.known[name] = true
+	}
+}
+
+// checkGlobal warns about a use of a global the program never defines.
+func (r *Resolver) checkGlobal(name *token.Token) {
+	if !r.checkGlobals || r.known[name.Lexeme] {
+		return
+	}
+
+	warning := fmt.Sprintf("[line %d] undefined variable '%s'", name.Line, name.Lexeme)
+	if suggestion, ok := closest(name.Lexeme, r.known); ok {
+		warning += fmt.Sprintf("; did you mean '%s'?", suggestion)
+	}
+	r.warnings = append(r.warnings, warning)
+}
+
+// Warnings returns the uses of globals that are never defined, in the order
+// they appear, and imports whose names could not be checked.
+func (r *Resolver) Warnings() []string {
+	return r.warnings
+}
+
+// closest returns the name nearest to name by edit distance, if one is close
+// enough to be a likely typo.
+func closest(name string, names map[string]bool) (string, bool) {
+	candidates := make([]string, 0, len(names))
+	for n := range names {
+		candidates = append(candidates, n)
+	}
+	sort.Strings(candidates)
+
+	// allow a typo every three letters, one in a shorter name, but none in
+	// a name of one letter
+	length := len([]rune(name))
+	limit := min(max(length/3, 1), length-1)
+
+	best, bestDistance := "", limit+1
+	for _, n := range candidates {
+		if d := editDistance(name, n); d < bestDistance {
+			best, bestDistance = n, d
+		}
+	}
+	return best, best != ""
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(t)]
+}
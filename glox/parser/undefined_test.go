@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func warnings(source string) []string {
+	stmts := parse(source)
+	r := NewResolver(NewInterpreter(stmts))
+	r.ResolveStmts(stmts)
+	return r.Warnings()
+}
+
+func TestUndefinedGlobals(t *testing.T) {
+	tests := []struct {
+		source   string
+		warnings []string
+	}{
+		// globals declared later, natives and imported names are all defined
+		{`fun f() { return g() + clock(); } fun g() { return 1; }`, nil},
+		{`import {square} from "lib"; print square(2);`, nil},
+		{`export const limit = 1; print limit; print Error;`, nil},
+		// locals never reach the check
+		{`fun f(count) { var total = count; return total; }`, nil},
+		{`var length = 3;
+print lenght;`, []string{"[line 2] undefined variable 'lenght'; did you mean 'length'?"}},
+		{`print claock();`, []string{"[line 1] undefined variable 'claock'; did you mean 'clock'?"}},
+		{`fun f() { missing = 1; }`, []string{"[line 1] undefined variable 'missing'"}},
+		// a one letter name is never taken for a typo
+		{`var a; print b;`, []string{"[line 1] undefined variable 'b'"}},
+		// nor is a short name two edits away from another
+		{`print nope;`, []string{"[line 1] undefined variable 'nope'"}},
+		{`print lem("abc");`, []string{"[line 1] undefined variable 'lem'; did you mean 'len'?"}},
+	}
+
+	for _, tt := range tests {
+		if got := warnings(tt.source); !reflect.DeepEqual(got, tt.warnings) {
+			t.Fatalf("%s - expected warnings %q, got %q", tt.source, tt.warnings, got)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"clock", "clock", 0},
+		{"clock", "clokc", 2},
+		{"lenght", "length", 2},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+		{"", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		if d := editDistance(tt.a, tt.b); d != tt.distance {
+			t.Fatalf("editDistance(%q, %q) expected %d, got %d", tt.a, tt.b, tt.distance, d)
+		}
+	}
+}
+
+func TestUndefinedGlobalsFromModules(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"util.lox": `export fun double(n) { return n * 2; }
+export const scale = 2;
+var hidden = 1;
+`,
+	})
+
+	tests := []struct {
+		source   string
+		warnings []string
+	}{
+		{`import "util.lox"; print double(scale);`, nil},
+		{`import "util.lox"; print util;`, []string{"[line 1] undefined variable 'util'"}},
+		{`import "util.lox"; print hidden;`, []string{"[line 1] undefined variable 'hidden'"}},
+		{`import "missing.lox"; print anything;`, []string{"[line 1] cannot check the names used from module 'missing.lox': cannot find module 'missing.lox' (looked for " + filepath.Join(dir, "missing.lox") + ")."}},
+	}
+
+	for _, tt := range tests {
+		stmts := parse(tt.source)
+		r := NewResolver(NewInterpreter(stmts, WithScriptPath(filepath.Join(dir, "main.lox"))))
+		r.ResolveStmts(stmts)
+		if got := r.Warnings(); !reflect.DeepEqual(got, tt.warnings) {
+			t.Fatalf("%s - expected warnings %q, got %q", tt.source, tt.warnings, got)
+		}
+	}
+}
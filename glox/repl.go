@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/parser"
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/scanner"
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
+)
+
+const (
+	PROMPT          = "-> "
+	CONTINUE_PROMPT = ".. "
+	historyFile     = ".glox_history"
+)
+
+const replHelp = `:quit     leave the repl
+:reset    forget every definition made in this session
+:env      list the global variables defined in this session
+:history  list the entries typed so far
+:help     show this message
+
+Input continues on the next line while parentheses or braces are open.
+On a terminal the arrow keys move within the line and recall earlier
+entries, showing the line breaks of recalled ones as ↵. Entries are saved to ~/.glox_history, or to $GLOX_HISTORY;
+set GLOX_HISTORY to nothing to keep no history file.`
+
+// repl is an interactive session; definitions made in one entry are kept
+// for the next ones until :reset.
+type repl struct {
+	in          lineReader
+	out         io.Writer
+	interpreter *parser.Interpreter
+	// builtins are the globals a new session starts with
+	builtins    map[string]any
+	history     []string
+	historyPath string
+}
+
+// newRepl starts a session reading entries from in. The history is loaded
+// from and saved to historyPath, unless it is "".
+func newRepl(in io.Reader, out io.Writer, historyPath string) *repl {
+	r := &repl{
+		in:          &plainReader{in: bufio.NewScanner(in), out: out},
+		out:         out,
+		historyPath: historyPath,
+	}
+	r.reset()
+
+	if f, ok := in.(*os.File); ok && isTerminal(int(f.Fd())) {
+		fd := int(f.Fd())
+		r.in = &lineEditor{
+			in:      bufio.NewReader(f),
+			out:     out,
+			history: &r.history,
+			raw:     func() (func(), error) { return makeRaw(fd) },
+		}
+	}
+
+	if r.historyPath != "" {
+		r.loadHistory()
+	}
+	return r
+}
+
+// historyPath returns the file the history is kept in: $GLOX_HISTORY when
+// it is set, so setting it empty keeps none, and ~/.glox_history otherwise.
+func historyPath() string {
+	if path, ok := os.LookupEnv("GLOX_HISTORY"); ok {
+		return path
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, historyFile)
+	}
+	return ""
+}
+
+func (r *repl) loadHistory() {
+	f, err := os.ReadFile(r.historyPath)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(f), "\n") {
+		if line != "" {
+			r.history = append(r.history, unescapeEntry(line))
+		}
+	}
+}
+
+// escapeEntry puts entry on one line of the history file, writing its line
+// breaks as \n and its backslashes as \\.
+func escapeEntry(entry string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(entry)
+}
+
+func unescapeEntry(line string) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			switch line[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(line[i])
+	}
+	return b.String()
+}
+
+func runPrompt(in io.Reader, out io.Writer) {
+	newRepl(in, out, historyPath()).loop()
+}
+
+func (r *repl) reset() {
+	r.interpreter = parser.NewInterpreter(nil, parser.WithOutput(r.out))
+	r.builtins = r.interpreter.Globals()
+}
+
+func (r *repl) loop() {
+	for {
+		entry, ok := r.read()
+		if !ok {
+			fmt.Fprintln(r.out)
+			return
+		}
+
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		r.remember(entry)
+
+		if strings.HasPrefix(entry, ":") {
+			if quit := r.command(entry); quit {
+				return
+			}
+			continue
+		}
+
+		r.eval(entry)
+	}
+}
+
+// read returns the next entry, reading more lines while it has unclosed
+// parentheses, braces, strings or block comments.
+func (r *repl) read() (string, bool) {
+	entry, ok := r.in.readLine(PROMPT)
+	if !ok {
+		return "", false
+	}
+
+	for incomplete(entry) {
+		line, ok := r.in.readLine(CONTINUE_PROMPT)
+		if !ok {
+			break
+		}
+		entry += "\n" + line
+	}
+
+	return entry, true
+}
+
+func (r *repl) remember(entry string) {
+	r.history = append(r.history, entry)
+	if r.historyPath == "" {
+		return
+	}
+
+	f, err := os.OpenFile(r.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, escapeEntry(entry))
+}
+
+func (r *repl) command(cmd string) (quit bool) {
+	switch cmd {
+	case ":quit", ":q":
+		return true
+
+	case ":reset":
+		r.reset()
+		fmt.Fprintln(r.out, "session reset")
+
+	case ":env":
+		// only what the session defined, not the natives
+		globals := r.interpreter.Globals()
+		names := make([]string, 0, len(globals))
+		for name, value := range globals {
+			if builtin, ok := r.builtins[name]; !ok || builtin != value {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Fprintf(r.out, "%s = %s\n", name, r.interpreter.Stringify(globals[name]))
+		}
+
+	case ":history":
+		for i, entry := range r.history {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, entry)
+		}
+
+	case ":help":
+		fmt.Fprintln(r.out, replHelp)
+
+	default:
+		fmt.Fprintf(r.out, "unknown command %s, try :help\n", cmd)
+	}
+
+	return false
+}
+
+// eval runs one entry, echoing the value of a bare expression. Errors are
+// reported and the session carries on.
+func (r *repl) eval(entry string) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	}()
+
+	s := scanner.NewScanner(entry)
+	s.ScanTokens()
+	if s.HadError() {
+		fmt.Fprintln(r.out, "error: could not scan the entry")
+		return
+	}
+
+	stmts := parser.NewParser(terminate(s.GetTokens())).Parse()
+	parser.NewResolver(r.interpreter).ResolveStmts(stmts)
+
+	if value, isExpr := r.interpreter.Eval(stmts); isExpr {
+		fmt.Fprintln(r.out, r.interpreter.Stringify(value))
+	}
+}
+
+// terminate adds the semicolon a bare expression such as "1 + 2" leaves
+// out, unless the entry already ends with one or with a block. Comments are
+// not tokens, so one after the last statement does not count.
+func terminate(tokens []token.Token) []token.Token {
+	end := len(tokens) - 1
+	if end == 0 || tokens[end-1].Type == token.SEMICOLON || tokens[end-1].Type == token.RIGHT_BRACE {
+		return tokens
+	}
+
+	semicolon := token.NewToken(token.SEMICOLON, ";", nil, tokens[end-1].Line)
+	return append(tokens[:end:end], *semicolon, tokens[end])
+}
+
+// incomplete reports whether source stops inside a string, a block comment
+// or an unclosed pair of parentheses or braces.
+func incomplete(source string) bool {
+	depth := 0
+	comments := 0
+
+	for i := 0; i < len(source); i++ {
+		c := source[i]
+		next := byte(0)
+		if i+1 < len(source) {
+			next = source[i+1]
+		}
+
+		switch {
+		case comments > 0:
+			if c == '/' && next == '*' {
+				comments++
+				i++
+			} else if c == '*' && next == '/' {
+				comments--
+				i++
+			}
+		case c == '/' && next == '*':
+			comments++
+			i++
+		case c == '/' && next == '/':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			end := strings.IndexByte(source[i+1:], '"')
+			if end < 0 {
+				return true
+			}
+			i += end + 1
+		case c == '(' || c == '{':
+			depth++
+		case c == ')' || c == '}':
+			depth--
+		}
+	}
+
+	return depth > 0 || comments > 0
+}
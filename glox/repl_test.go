@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		source     string
+		incomplete bool
+	}{
+		{`print 1;`, false},
+		{`fun f() {`, true},
+		{`fun f() { if (true) {`, true},
+		{`fun f() { if (true) { } }`, false},
+		{`print (1 +`, true},
+		{`print "open`, true},
+		{`print "a { (";`, false},
+		{"print \"two\nlines\";", false},
+		{`/* open`, true},
+		{`/* a /* nested */ comment`, true},
+		{`/* a /* nested */ comment */ print 1;`, false},
+		{`print 1; // {`, false},
+		{"// {\nprint 1;", false},
+	}
+
+	for _, tt := range tests {
+		if got := incomplete(tt.source); got != tt.incomplete {
+			t.Fatalf("incomplete(%q) expected %v, got %v", tt.source, tt.incomplete, got)
+		}
+	}
+}
+
+// session runs the repl on input and returns what it printed, without the
+// prompts.
+func session(t *testing.T, input string, historyPath string) string {
+	t.Helper()
+	var out bytes.Buffer
+	newRepl(strings.NewReader(input), &out, historyPath).loop()
+
+	text := strings.ReplaceAll(out.String(), CONTINUE_PROMPT, "")
+	return strings.ReplaceAll(text, PROMPT, "")
+}
+
+func TestReplSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"definitions are kept", "var a = 1;\na + 1\n", "2\n\n"},
+		{"multi-line entries", "fun f(x) {\n  return x * 2;\n}\nf(4)\n", "8\n\n"},
+		{"errors are reported and the session goes on", "var a = 1;\nprint nope;\nprint a;\n", "error: undefined variable 'nope'.\n1\n\n"},
+		{"parse errors too", "var = 1;\nprint 2;\n", "error: IDENT: Expect variable name.\n2\n\n"},
+		{"and scan errors", "print 1 @ 2;\nprint 2;\n", "error: could not scan the entry\n2\n\n"},
+		{"a comment after a statement", "print 1; // c\n", "1\n\n"},
+		{"a comment after an expression", "1 + 1 // c\n", "2\n\n"},
+		{"env lists only the session's globals", "var b = 2;\nfun f() {}\n:env\n", "b = 2\nf = <fn f>\n\n"},
+		{"a redefined native is listed", "var clock = 1;\n:env\n", "clock = 1\n\n"},
+		{"reset forgets definitions", "var a = 1;\n:reset\n:env\nprint 3;\n", "session reset\n3\n\n"},
+		{"history", "print 1;\n:history\n", "1\n   1  print 1;\n   2  :history\n\n"},
+		{"quit", ":quit\nprint 1;\n", ""},
+		{"unknown commands", ":nope\n", "unknown command :nope, try :help\n\n"},
+	}
+
+	for _, tt := range tests {
+		if got := session(t, tt.input, ""); got != tt.expected {
+			t.Fatalf("%s - output wrong, expected:\n%q\ngot:\n%q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestReplHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("print 0;\nfun g() { // \\\\ \\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got := session(t, "fun f() { // c\n}\n:history\n", path)
+	if expected := "   1  print 0;\n   2  fun g() { // \\ \n}\n   3  fun f() { // c\n}\n   4  :history\n\n"; got != expected {
+		t.Fatalf("history wrong, expected:\n%q\ngot:\n%q", expected, got)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "print 0;\nfun g() { // \\\\ \\n}\nfun f() { // c\\n}\n:history\n"; string(saved) != expected {
+		t.Fatalf("saved history wrong, expected:\n%q\ngot:\n%q", expected, string(saved))
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	history := []string{"print 1;", "fun f() { // g\n}"}
+	tests := []struct {
+		name string
+		keys string
+		line string
+		ok   bool
+	}{
+		{"typing", "print 2;\r", "print 2;", true},
+		{"backspace", "print 23\x7f;\r", "print 2;", true},
+		{"left and insert", "print ;\x1b[D2\r", "print 2;", true},
+		{"home and end", "rint\x1b[Hp\x1b[F 1;\r", "print 1;", true},
+		{"ctrl-a and ctrl-e", "rint\x01p\x05 1;\r", "print 1;", true},
+		{"delete", "pprint 1;\x01\x1b[3~\r", "print 1;", true},
+		{"ctrl-k and ctrl-u", "print 1; junk\x1b[D\x1b[D\x1b[D\x1b[D\x1b[D\x0b\r", "print 1;", true},
+		{"kill to start", "junk print 1;\x02\x02\x02\x02\x02\x02\x02\x02\x15\r", "print 1;", true},
+		{"up recalls the newest entry", "\x1b[A\r", "fun f() { // g\n}", true},
+		{"up twice", "\x1b[A\x1b[A\r", "print 1;", true},
+		{"up stops at the oldest", "\x1b[A\x1b[A\x1b[A\r", "print 1;", true},
+		{"down returns to the draft", "pr\x1b[A\x1b[B\r", "pr", true},
+		{"ctrl-c drops the line", "print 1;\x03", "", true},
+		{"ctrl-d on an empty line quits", "\x04", "", false},
+		{"end of input", "", "", false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := &lineEditor{in: bufio.NewReader(strings.NewReader(tt.keys)), out: &out, history: &history}
+		line, ok := e.readLine(PROMPT)
+		if line != tt.line || ok != tt.ok {
+			t.Fatalf("%s - expected %q, %v, got %q, %v", tt.name, tt.line, tt.ok, line, ok)
+		}
+	}
+}
@@ -3,13 +3,15 @@ package scanner
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/Martin-Martinez4/crafting-interpreters/glox/errorhandling"
 	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
 )
 
 var keywords = map[string]token.TokenType{
-	"var": token.VAR,
+	"var":   token.VAR,
+	"const": token.CONST,
 
 	"and":   token.AND,
 	"or":    token.OR,
@@ -24,20 +26,33 @@ var keywords = map[string]token.TokenType{
 	"return": token.RETURN,
 
 	"class": token.CLASS,
+	"trait": token.TRAIT,
 	"this":  token.THIS,
 	"super": token.SUPER,
 
 	"print": token.PRINT,
 	"nil":   token.NIL,
+
+	"import": token.IMPORT,
+	"export": token.EXPORT,
+
+	"try":     token.TRY,
+	"catch":   token.CATCH,
+	"finally": token.FINALLY,
+	"throw":   token.THROW,
 }
 
 type Scanner struct {
 	source   string
 	tokens   []token.Token
+	comments []token.Comment
 	start    int
 	current  int
 	line     int
-	keywords *(map[string]token.TokenType)
+	// line on which the token being scanned starts
+	startLine int
+	keywords  *(map[string]token.TokenType)
+	hadError  bool
 }
 
 func NewScanner(source string) *Scanner {
@@ -55,6 +70,16 @@ func (s *Scanner) GetTokens() []token.Token {
 	return s.tokens
 }
 
+// HadError reports whether any error was found while scanning.
+func (s *Scanner) HadError() bool {
+	return s.hadError
+}
+
+func (s *Scanner) error(line int, message string) {
+	s.hadError = true
+	errorhandling.ReportError(line, "", message)
+}
+
 func (s *Scanner) isAtEnd() bool {
 	return s.current >= len(s.source)
 }
@@ -66,12 +91,37 @@ func (s *Scanner) ScanTokens() {
 
 	}
 
-	s.tokens = append(s.tokens, *token.NewToken(token.EOF, "", nil, s.line))
+	s.start = s.current
+	s.startLine = s.line
+	s.appendToken(token.NewToken(token.EOF, "", nil, s.line))
+}
+
+// appendToken adds t to the token list, attaching any comments that were
+// scanned since the previous token as its leading trivia.
+func (s *Scanner) appendToken(t *token.Token) {
+	t.Line = s.startLine
+	t.Column = s.start - strings.LastIndexByte(s.source[:s.start], '\n')
+	t.Leading = s.comments
+	s.comments = nil
+	s.tokens = append(s.tokens, *t)
+}
+
+// addComment keeps a comment starting on line as trivia; a comment that
+// shares a line with the previous token trails it, anything else leads the
+// next token.
+func (s *Scanner) addComment(text string, line int) {
+	c := token.Comment{Text: text, Line: line}
+	c.Doc = strings.HasPrefix(text, "///") && !strings.HasPrefix(text, "////")
+	if n := len(s.tokens); n > 0 && len(s.comments) == 0 && s.tokens[n-1].Line == line {
+		s.tokens[n-1].Trailing = append(s.tokens[n-1].Trailing, c)
+		return
+	}
+	s.comments = append(s.comments, c)
 }
 
 func (s *Scanner) skipWhiteSpace() byte {
 	cc := s.advance()
-	for cc == ' ' || cc == '\t' || cc == '\r' {
+	for (cc == ' ' || cc == '\t' || cc == '\r') && !s.isAtEnd() {
 		s.start = s.current
 		cc = s.advance()
 	}
@@ -82,8 +132,12 @@ func (s *Scanner) skipWhiteSpace() byte {
 func (s *Scanner) scanToken() {
 	// c := s.advance()
 	c := s.skipWhiteSpace()
+	s.startLine = s.line
 
 	switch c {
+	case ' ', '\t', '\r':
+		// trailing whitespace at the end of the source
+
 	case '(':
 		s.addToken(token.LEFT_PAREN, nil)
 
@@ -96,6 +150,12 @@ func (s *Scanner) scanToken() {
 	case '}':
 		s.addToken(token.RIGHT_BRACE, nil)
 
+	case '[':
+		s.addToken(token.LEFT_BRACKET, nil)
+
+	case ']':
+		s.addToken(token.RIGHT_BRACKET, nil)
+
 	case ',':
 		s.addToken(token.COMMA, nil)
 
@@ -149,6 +209,9 @@ func (s *Scanner) scanToken() {
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+			s.addComment(s.source[s.start:s.current], s.line)
+		} else if s.match('*') {
+			s.blockComment()
 		} else {
 			s.addToken(token.SLASH, nil)
 		}
@@ -179,7 +242,7 @@ func (s *Scanner) scanToken() {
 			}
 
 			nt := token.NewToken(token.NUMBER, number, f, s.line)
-			s.tokens = append(s.tokens, *nt)
+			s.appendToken(nt)
 
 		} else if IsAlpha(c) {
 
@@ -190,14 +253,20 @@ func (s *Scanner) scanToken() {
 			t, ok := (*s.keywords)[text]
 			if !ok {
 				ident := (s.source[s.start:s.current])
-				s.tokens = append(s.tokens, *token.NewToken(token.IDENTIFIER, ident, "", s.line))
+				s.appendToken(token.NewToken(token.IDENTIFIER, ident, "", s.line))
 			} else {
 
-				s.tokens = append(s.tokens, *token.NewToken(t, text, "", s.line))
+				s.appendToken(token.NewToken(t, text, "", s.line))
 
 			}
+		} else if c == '#' && IsAlpha(s.peek()) {
+			// a private name, like "#count"
+			for isAlphaNumeric(s.peek()) {
+				s.advance()
+			}
+			s.appendToken(token.NewToken(token.IDENTIFIER, s.source[s.start:s.current], "", s.line))
 		} else {
-			fmt.Errorf("unknown character '%v' at line %d", string(c), s.line)
+			s.error(s.line, fmt.Sprintf("unknown character '%v'", string(c)))
 		}
 
 	}
@@ -212,7 +281,7 @@ func (s *Scanner) advance() byte {
 
 func (s *Scanner) addToken(t token.TokenType, literal any) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, *token.NewToken(t, text, literal, s.line))
+	s.appendToken(token.NewToken(t, text, literal, s.line))
 
 }
 
@@ -248,6 +317,32 @@ func (s *Scanner) peekNext() byte {
 	}
 }
 
+// blockComment skips a /* ... */ comment, which may nest and span lines.
+func (s *Scanner) blockComment() {
+	line := s.line
+	depth := 1
+	for depth > 0 {
+		if s.isAtEnd() {
+			s.error(line, "unterminated block comment")
+			return
+		}
+
+		c := s.advance()
+		switch {
+		case c == '\n':
+			s.line++
+		case c == '/' && s.peek() == '*':
+			s.advance()
+			depth++
+		case c == '*' && s.peek() == '/':
+			s.advance()
+			depth--
+		}
+	}
+
+	s.addComment(s.source[s.start:s.current], line)
+}
+
 func (s *Scanner) handleString() {
 	for s.peek() != '"' && !s.isAtEnd() {
 		if s.peek() == '\n' {
@@ -257,7 +352,7 @@ func (s *Scanner) handleString() {
 	}
 
 	if s.isAtEnd() {
-		errorhandling.ReportAndExit(s.line, "", "unterminated string")
+		s.error(s.line, "unterminated string")
 		return
 	}
 	s.advance()
@@ -260,3 +260,89 @@ func TestStringLiterals(t *testing.T) {
 		}
 	}
 }
+
+func TestCommentTrivia(t *testing.T) {
+	input := `// leading
+var a = 1; // trailing
+// at end`
+
+	s := NewScanner(input)
+	s.ScanTokens()
+
+	first := s.tokens[0]
+	if len(first.Leading) != 1 || first.Leading[0].Text != "// leading" || first.Leading[0].Line != 1 {
+		t.Fatalf("leading comment wrong, got: %+v", first.Leading)
+	}
+
+	semicolon := s.tokens[4]
+	if len(semicolon.Trailing) != 1 || semicolon.Trailing[0].Text != "// trailing" {
+		t.Fatalf("trailing comment wrong, got: %+v", semicolon.Trailing)
+	}
+
+	eof := s.tokens[len(s.tokens)-1]
+	if eof.Type != token.EOF || len(eof.Leading) != 1 || eof.Leading[0].Text != "// at end" {
+		t.Fatalf("comment before EOF wrong, got: %+v", eof.Leading)
+	}
+}
+
+func TestBlockAndDocComments(t *testing.T) {
+	input := `/* outer
+	/* nested */
+	still a comment */
+/// doc
+fun`
+
+	s := NewScanner(input)
+	s.ScanTokens()
+
+	fun := s.tokens[0]
+	if fun.Type != token.FUN || fun.Line != 5 {
+		t.Fatalf("expected FUN on line 5, got: %q on line %d", fun.Type, fun.Line)
+	}
+
+	if len(fun.Leading) != 2 {
+		t.Fatalf("expected 2 leading comments, got: %+v", fun.Leading)
+	}
+	if fun.Leading[0].Line != 1 || fun.Leading[0].Doc {
+		t.Fatalf("block comment wrong, got: %+v", fun.Leading[0])
+	}
+	if fun.Leading[1].Text != "/// doc" || !fun.Leading[1].Doc {
+		t.Fatalf("doc comment wrong, got: %+v", fun.Leading[1])
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `var s = "a
+b";
+  print s;`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.VAR, 1, 1},
+		{token.IDENTIFIER, 1, 5},
+		{token.EQUAL, 1, 7},
+		{token.STRING, 1, 9},
+		{token.SEMICOLON, 2, 3},
+		{token.PRINT, 3, 3},
+		{token.IDENTIFIER, 3, 9},
+		{token.SEMICOLON, 3, 10},
+		{token.EOF, 3, 11},
+	}
+
+	s := NewScanner(input)
+	s.ScanTokens()
+
+	for i, tt := range tests {
+		tok := s.tokens[i]
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - Type wrong, expected: %q got : %q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong, expected: %d:%d got : %d:%d", i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
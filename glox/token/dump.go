@@ -0,0 +1,76 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// dumped is the stable shape of a token in a dump.
+type dumped struct {
+	Type    TokenType `json:"type"`
+	Lexeme  string    `json:"lexeme"`
+	Literal any       `json:"literal"`
+	Line    int       `json:"line"`
+	Column  int       `json:"column"`
+}
+
+func dump(t Token) dumped {
+	d := dumped{Type: t.Type, Lexeme: t.Lexeme, Line: t.Line, Column: t.Column}
+	if t.Type == NUMBER || t.Type == STRING {
+		d.Literal = t.Literal
+	}
+	return d
+}
+
+// WriteTable prints one token per line as position, type, quoted lexeme and
+// literal, in fixed width columns so dumps can be diffed.
+func WriteTable(w io.Writer, tokens []Token) error {
+	if _, err := fmt.Fprintf(w, "%-9s %-13s %-24s %s\n", "POS", "TYPE", "LEXEME", "LITERAL"); err != nil {
+		return err
+	}
+
+	for _, t := range tokens {
+		d := dump(t)
+
+		literal := ""
+		switch l := d.Literal.(type) {
+		case float64:
+			literal = strconv.FormatFloat(l, 'f', -1, 64)
+		case string:
+			literal = strconv.Quote(l)
+		}
+
+		pos := fmt.Sprintf("%d:%d", d.Line, d.Column)
+		if _, err := fmt.Fprintf(w, "%-9s %-13s %-24s %s\n", pos, d.Type, strconv.Quote(d.Lexeme), literal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON prints the tokens as a JSON array with one token per line.
+func WriteJSON(w io.Writer, tokens []Token) error {
+	if _, err := io.WriteString(w, "[\n"); err != nil {
+		return err
+	}
+
+	for i, t := range tokens {
+		b, err := json.Marshal(dump(t))
+		if err != nil {
+			return err
+		}
+
+		sep := ",\n"
+		if i == len(tokens)-1 {
+			sep = "\n"
+		}
+		if _, err := fmt.Fprintf(w, "  %s%s", b, sep); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
@@ -4,11 +4,27 @@ import "fmt"
 
 type TokenType string
 
+// Comment is a source comment kept as trivia on a token so that tools like
+// the formatter can put it back where it was written. Doc marks a "///"
+// comment documenting the declaration that follows it.
+type Comment struct {
+	Text string
+	Line int
+	Doc  bool
+}
+
 type Token struct {
 	Type    TokenType
 	Lexeme  string
 	Literal any
 	Line    int
+	// Column is the 1-based byte offset of the token in its first line.
+	Column int
+
+	// Leading holds the comments written on the lines before the token,
+	// Trailing the ones that follow it on the same line.
+	Leading  []Comment
+	Trailing []Comment
 }
 
 func NewToken(tt TokenType, lexeme string, literal any, line int) *Token {
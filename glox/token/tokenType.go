@@ -1,17 +1,19 @@
 package token
 
 const (
-	LEFT_PAREN  = "("
-	RIGHT_PAREN = ")"
-	LEFT_BRACE  = "{"
-	RIGHT_BRACE = "}"
-	COMMA       = ","
-	DOT         = "."
-	MINUS       = "-"
-	PLUS        = "+"
-	SEMICOLON   = ";"
-	SLASH       = "/"
-	STAR        = "*"
+	LEFT_PAREN    = "("
+	RIGHT_PAREN   = ")"
+	LEFT_BRACE    = "{"
+	RIGHT_BRACE   = "}"
+	LEFT_BRACKET  = "["
+	RIGHT_BRACKET = "]"
+	COMMA         = ","
+	DOT           = "."
+	MINUS         = "-"
+	PLUS          = "+"
+	SEMICOLON     = ";"
+	SLASH         = "/"
+	STAR          = "*"
 
 	BANG          = "!"
 	BANG_EQUAL    = "!="
@@ -29,6 +31,7 @@ const (
 	// Keywords
 	FUN    = "FUN"
 	VAR    = "VAR"
+	CONST  = "CONST"
 	TRUE   = "TRUE"
 	FALSE  = "FALSE"
 	IF     = "IF"
@@ -36,12 +39,20 @@ const (
 	RETURN = "RETURN"
 	AND    = "AND"
 	CLASS  = "CLASS"
+	TRAIT  = "TRAIT"
 	FOR    = "FOR"
 	OR     = "OR"
 	PRINT  = "PRINT"
 	SUPER  = "SUPER"
 	THIS   = "THIS"
 	WHILE  = "WHILE"
+	IMPORT = "IMPORT"
+	EXPORT = "EXPORT"
+
+	TRY     = "TRY"
+	CATCH   = "CATCH"
+	FINALLY = "FINALLY"
+	THROW   = "THROW"
 
 	EOF = "EOF"
 
@@ -0,0 +1,463 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// A .loxc file is a header followed by the script's function prototype:
+//
+//	magic    "LOXC"
+//	version  uint16, big endian
+//	checksum uint32, big endian, CRC-32 (IEEE) of everything after it
+//	function
+//
+// A function prototype is its name, arity, upvalue count, code, line table
+// and constant pool. The line table is run length encoded as pairs of line
+// and count. Every constant starts with a tag byte; a function constant is
+// the prototype of a nested function, written in place. Integers and
+// lengths are unsigned varints.
+const (
+	BYTECODE_MAGIC   = "LOXC"
+	BYTECODE_VERSION = 1
+	headerSize       = len(BYTECODE_MAGIC) + 2 + 4
+)
+
+const (
+	TAG_NIL byte = iota
+	TAG_FALSE
+	TAG_TRUE
+	TAG_NUMBER
+	TAG_STRING
+	TAG_FUNCTION
+)
+
+var (
+	ErrNotBytecode = errors.New("not a .loxc file")
+	ErrCorrupt     = errors.New("corrupt .loxc file")
+)
+
+// IsBytecode reports whether data starts like a .loxc file.
+func IsBytecode(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(BYTECODE_MAGIC))
+}
+
+// WriteBytecode writes function, the result of Compile, as a .loxc file.
+func WriteBytecode(w io.Writer, function *Function) error {
+	var body bytes.Buffer
+	if err := writeFunction(&body, function); err != nil {
+		return err
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, BYTECODE_MAGIC...)
+	header = binary.BigEndian.AppendUint16(header, BYTECODE_VERSION)
+	header = binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(body.Bytes()))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(body.Bytes())
+	return err
+}
+
+func writeUvarint(w *bytes.Buffer, n int) {
+	w.Write(binary.AppendUvarint(nil, uint64(n)))
+}
+
+func writeString(w *bytes.Buffer, s string) {
+	writeUvarint(w, len(s))
+	w.WriteString(s)
+}
+
+func writeFunction(w *bytes.Buffer, function *Function) error {
+	writeString(w, function.Name)
+	writeUvarint(w, function.Arity)
+	writeUvarint(w, function.UpvalueCount)
+
+	chunk := &function.Chunk
+	writeUvarint(w, len(chunk.Code))
+	w.Write(chunk.Code)
+
+	runs := [][2]int{}
+	for _, line := range chunk.Lines {
+		if len(runs) > 0 && runs[len(runs)-1][0] == line {
+			runs[len(runs)-1][1]++
+		} else {
+			runs = append(runs, [2]int{line, 1})
+		}
+	}
+	writeUvarint(w, len(runs))
+	for _, run := range runs {
+		writeUvarint(w, run[0])
+		writeUvarint(w, run[1])
+	}
+
+	writeUvarint(w, len(chunk.Constants))
+	for _, constant := range chunk.Constants {
+		switch c := constant.(type) {
+		case nil:
+			w.WriteByte(TAG_NIL)
+		case bool:
+			if c {
+				w.WriteByte(TAG_TRUE)
+			} else {
+				w.WriteByte(TAG_FALSE)
+			}
+		case float64:
+			w.WriteByte(TAG_NUMBER)
+			w.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(c)))
+		case string:
+			w.WriteByte(TAG_STRING)
+			writeString(w, c)
+		case *Function:
+			w.WriteByte(TAG_FUNCTION)
+			if err := writeFunction(w, c); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("cannot serialize constant %s", Stringify(constant))
+		}
+	}
+	return nil
+}
+
+// ReadBytecode loads a program written by WriteBytecode. Files with another
+// magic or version, a checksum mismatch or malformed contents are rejected.
+func ReadBytecode(r io.Reader) (*Function, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsBytecode(data) {
+		return nil, ErrNotBytecode
+	}
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("%w: truncated header", ErrCorrupt)
+	}
+
+	version := binary.BigEndian.Uint16(data[4:6])
+	if version != BYTECODE_VERSION {
+		return nil, fmt.Errorf("unsupported .loxc version %d, this glox reads version %d", version, BYTECODE_VERSION)
+	}
+
+	body := data[headerSize:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(data[6:10]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+	}
+
+	d := &decoder{r: bytes.NewReader(body)}
+	function := d.function()
+	if d.err == nil {
+		if _, err := d.r.ReadByte(); err != io.EOF {
+			d.fail("trailing data")
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, d.err)
+	}
+	return function, nil
+}
+
+// decoder reads a function prototype, keeping the first error so the
+// reading code does not have to check after every field.
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (d *decoder) fail(format string, a ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, a...)
+	}
+}
+
+func (d *decoder) uvarint() int {
+	if d.err != nil {
+		return 0
+	}
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil || n > math.MaxInt32 {
+		d.fail("bad integer")
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > d.r.Len() {
+		d.fail("truncated")
+		return nil
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.fail("truncated")
+		return nil
+	}
+	return b
+}
+
+func (d *decoder) byte() byte {
+	b := d.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(d.uvarint()))
+}
+
+func (d *decoder) function() *Function {
+	function := &Function{
+		Name:         d.string(),
+		Arity:        d.uvarint(),
+		UpvalueCount: d.uvarint(),
+	}
+	if function.Arity > 255 || function.UpvalueCount > UINT8_COUNT {
+		d.fail("function %s has too many parameters or upvalues", function)
+	}
+
+	chunk := &function.Chunk
+	chunk.Code = d.bytes(d.uvarint())
+
+	runs := d.uvarint()
+	for i := 0; i < runs && d.err == nil; i++ {
+		line, count := d.uvarint(), d.uvarint()
+		if len(chunk.Lines)+count > len(chunk.Code) {
+			d.fail("line table longer than code")
+			break
+		}
+		for j := 0; j < count; j++ {
+			chunk.Lines = append(chunk.Lines, line)
+		}
+	}
+	if d.err == nil && len(chunk.Lines) != len(chunk.Code) {
+		d.fail("line table shorter than code")
+	}
+
+	constants := d.uvarint()
+	for i := 0; i < constants && d.err == nil; i++ {
+		switch tag := d.byte(); tag {
+		case TAG_NIL:
+			chunk.Constants = append(chunk.Constants, nil)
+		case TAG_FALSE:
+			chunk.Constants = append(chunk.Constants, false)
+		case TAG_TRUE:
+			chunk.Constants = append(chunk.Constants, true)
+		case TAG_NUMBER:
+			bits := d.bytes(8)
+			if bits != nil {
+				chunk.Constants = append(chunk.Constants, math.Float64frombits(binary.BigEndian.Uint64(bits)))
+			}
+		case TAG_STRING:
+			chunk.Constants = append(chunk.Constants, d.string())
+		case TAG_FUNCTION:
+			chunk.Constants = append(chunk.Constants, d.function())
+		default:
+			d.fail("unknown constant tag %d", tag)
+		}
+	}
+
+	if d.err == nil {
+		d.verify(function)
+	}
+	return function
+}
+
+// verify checks that every instruction of function is complete, jumps to
+// the start of an instruction in its chunk and only refers to constants of
+// the right type and upvalues the function has. It then follows every path
+// through the code, as the vm would run it, so that no instruction pops a
+// value that is not there, uses a local slot above the top of the stack or
+// runs off the end of the chunk.
+func (d *decoder) verify(function *Function) {
+	chunk := &function.Chunk
+	constant := func(offset int) Value {
+		index := int(chunk.Code[offset])
+		if index >= len(chunk.Constants) {
+			d.fail("%s: constant %d out of range at %04d", function, index, offset)
+			return nil
+		}
+		return chunk.Constants[index]
+	}
+	upvalue := func(index int, offset int) {
+		if index >= function.UpvalueCount {
+			d.fail("%s: upvalue %d out of range at %04d", function, index, offset)
+		}
+	}
+
+	// sizes holds the size of the instruction at each offset, 0 for offsets
+	// inside an instruction
+	sizes := make([]int, len(chunk.Code))
+	for offset := 0; offset < len(chunk.Code) && d.err == nil; {
+		op := OpCode(chunk.Code[offset])
+		size := 1
+		switch op {
+		case OP_CONSTANT, OP_GET_LOCAL, OP_SET_LOCAL, OP_GET_GLOBAL, OP_SET_GLOBAL,
+			OP_DEFINE_GLOBAL, OP_CALL, OP_GET_UPVALUE, OP_SET_UPVALUE, OP_CLASS,
+			OP_GET_PROPERTY, OP_SET_PROPERTY, OP_METHOD, OP_GET_SUPER, OP_CLOSURE:
+			size = 2
+		case OP_JUMP_IF_FALSE, OP_JUMP, OP_LOOP, OP_INVOKE, OP_SUPER_INVOKE:
+			size = 3
+		default:
+			if int(op) >= len(opNames) {
+				d.fail("%s: unknown opcode %d at %04d", function, byte(op), offset)
+				return
+			}
+		}
+		if offset+size > len(chunk.Code) {
+			d.fail("%s: truncated instruction at %04d", function, offset)
+			return
+		}
+
+		switch op {
+		case OP_CONSTANT:
+			constant(offset + 1)
+		case OP_GET_UPVALUE, OP_SET_UPVALUE:
+			upvalue(int(chunk.Code[offset+1]), offset)
+		case OP_GET_GLOBAL, OP_SET_GLOBAL, OP_DEFINE_GLOBAL, OP_CLASS, OP_GET_PROPERTY,
+			OP_SET_PROPERTY, OP_METHOD, OP_GET_SUPER, OP_INVOKE, OP_SUPER_INVOKE:
+			if _, ok := constant(offset + 1).(string); !ok && d.err == nil {
+				d.fail("%s: %s needs a name at %04d", function, op, offset)
+			}
+		case OP_CLOSURE:
+			nested, ok := constant(offset + 1).(*Function)
+			if !ok {
+				d.fail("%s: OP_CLOSURE needs a function at %04d", function, offset)
+				return
+			}
+			size += 2 * nested.UpvalueCount
+			if offset+size > len(chunk.Code) {
+				d.fail("%s: truncated instruction at %04d", function, offset)
+				return
+			}
+			for i := offset + 2; i < offset+size; i += 2 {
+				switch chunk.Code[i] {
+				case 0:
+					upvalue(int(chunk.Code[i+1]), offset)
+				case 1:
+				default:
+					d.fail("%s: bad upvalue of a closure at %04d", function, offset)
+				}
+			}
+		}
+		sizes[offset] = size
+		offset += size
+	}
+	if d.err == nil {
+		d.followStack(function, sizes)
+	}
+}
+
+// stackEffect returns how many values the instruction at offset pops and
+// how many it pushes.
+func stackEffect(code []byte, offset int) (pops int, pushes int) {
+	switch OpCode(code[offset]) {
+	case OP_CONSTANT, OP_NIL, OP_TRUE, OP_FALSE, OP_GET_LOCAL, OP_GET_GLOBAL,
+		OP_GET_UPVALUE, OP_CLOSURE, OP_CLASS:
+		return 0, 1
+	case OP_POP, OP_DEFINE_GLOBAL, OP_PRINT, OP_CLOSE_UPVALUE, OP_RETURN:
+		return 1, 0
+	case OP_SET_LOCAL, OP_SET_GLOBAL, OP_SET_UPVALUE, OP_NOT, OP_NEGATE,
+		OP_JUMP_IF_FALSE, OP_GET_PROPERTY:
+		return 1, 1
+	case OP_EQUAL, OP_GREATER, OP_LESS, OP_ADD, OP_SUBTRACT, OP_MULTIPLY, OP_DIVIDE,
+		OP_SET_PROPERTY, OP_METHOD, OP_INHERIT, OP_GET_SUPER:
+		return 2, 1
+	case OP_CALL:
+		return int(code[offset+1]) + 1, 1
+	case OP_INVOKE:
+		return int(code[offset+2]) + 1, 1
+	case OP_SUPER_INVOKE:
+		return int(code[offset+2]) + 2, 1
+	}
+	return 0, 0
+}
+
+// followStack works out the height of the stack before every reachable
+// instruction of function, counting from the slot of the function itself,
+// and fails when two paths reach an instruction with different heights.
+func (d *decoder) followStack(function *Function, sizes []int) {
+	code := function.Chunk.Code
+	heights := make([]int, len(code))
+	for n := range heights {
+		heights[n] = -1
+	}
+
+	pending := []int{0}
+	heights[0] = function.Arity + 1
+	reach := func(from int, offset int, height int) {
+		switch {
+		case offset >= len(code):
+			d.fail("%s: runs off the end of the chunk at %04d", function, from)
+		case sizes[offset] == 0:
+			d.fail("%s: jump into the middle of an instruction at %04d", function, from)
+		case heights[offset] == -1:
+			heights[offset] = height
+			pending = append(pending, offset)
+		case heights[offset] != height:
+			d.fail("%s: stack height differs between paths to %04d", function, offset)
+		}
+	}
+
+	for len(pending) > 0 && d.err == nil {
+		offset := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		height := heights[offset]
+
+		op := OpCode(code[offset])
+		pops, pushes := stackEffect(code, offset)
+		if pops > height {
+			d.fail("%s: %s pops more than the stack holds at %04d", function, op, offset)
+			return
+		}
+
+		size := sizes[offset]
+		switch op {
+		case OP_GET_LOCAL, OP_SET_LOCAL:
+			if slot := int(code[offset+1]); slot >= height {
+				d.fail("%s: local slot %d out of range at %04d", function, slot, offset)
+				return
+			}
+		case OP_CLOSURE:
+			for i := offset + 2; i < offset+size; i += 2 {
+				if code[i] == 1 && int(code[i+1]) >= height {
+					d.fail("%s: closure captures local slot %d out of range at %04d", function, code[i+1], offset)
+					return
+				}
+			}
+		}
+		height += pushes - pops
+
+		switch op {
+		case OP_RETURN:
+			continue
+		case OP_JUMP, OP_JUMP_IF_FALSE, OP_LOOP:
+			jump := int(code[offset+1])<<8 | int(code[offset+2])
+			if op == OP_LOOP {
+				jump = -jump
+			}
+			target := offset + size + jump
+			if target < 0 {
+				d.fail("%s: jump out of the chunk at %04d", function, offset)
+				return
+			}
+			reach(offset, target, height)
+			if op != OP_JUMP_IF_FALSE {
+				continue
+			}
+		}
+		reach(offset, offset+size, height)
+	}
+}
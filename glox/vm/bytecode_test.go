@@ -0,0 +1,193 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/scanner"
+)
+
+func compileSource(t *testing.T, source string) *Function {
+	t.Helper()
+
+	s := scanner.NewScanner(source)
+	s.ScanTokens()
+	function, err := Compile(s.GetTokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return function
+}
+
+func encode(t *testing.T, function *Function) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+	if err := WriteBytecode(&b, function); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestBytecodeRoundTrip(t *testing.T) {
+	scripts, err := filepath.Glob("../scripts/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range scripts {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := scanner.NewScanner(string(source))
+		s.ScanTokens()
+		function, err := Compile(s.GetTokens())
+		if err != nil {
+			continue
+		}
+
+		loaded, err := ReadBytecode(bytes.NewReader(encode(t, function)))
+		if err != nil {
+			t.Fatalf("%s - %v", path, err)
+		}
+		if !reflect.DeepEqual(function, loaded) {
+			t.Fatalf("%s - loaded function differs from the compiled one", path)
+		}
+	}
+}
+
+func TestBytecodeRuns(t *testing.T) {
+	function := compileSource(t, `
+fun greet(name) { return "hi " + name; }
+class A { init(n) { this.n = n; } get() { return this.n * 2.5; } }
+print greet("bob");
+print A(2).get();
+print nil == false;
+`)
+
+	loaded, err := ReadBytecode(bytes.NewReader(encode(t, function)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	vm := New()
+	vm.SetOutput(&b)
+	if err := vm.Interpret(loaded); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "hi bob\n5\nfalse\n"; b.String() != expected {
+		t.Fatalf("output wrong, expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+// resum fixes the checksum of data after its body was changed on purpose.
+func resum(data []byte) []byte {
+	binary.BigEndian.PutUint32(data[6:10], crc32.ChecksumIEEE(data[headerSize:]))
+	return data
+}
+
+func TestBytecodeRejectsCorruptFiles(t *testing.T) {
+	good := encode(t, compileSource(t, `fun f(a) { if (a) return "yes"; return 1; } print f(true); print "x";`))
+	// the last constant is "x": a string tag, its length and the letter
+	corrupt := func(change func(data []byte) []byte) []byte {
+		return change(bytes.Clone(good))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", []byte{}, ErrNotBytecode},
+		{"source", []byte("print 1;"), ErrNotBytecode},
+		{"short header", good[:7], ErrCorrupt},
+		{"flipped byte", corrupt(func(d []byte) []byte { d[len(d)-3] ^= 0xff; return d }), ErrCorrupt},
+		{"truncated", corrupt(func(d []byte) []byte { return resum(d[:len(d)-4]) }), ErrCorrupt},
+		{"trailing data", corrupt(func(d []byte) []byte { return resum(append(d, 0)) }), ErrCorrupt},
+		{"unknown tag", corrupt(func(d []byte) []byte { d[len(d)-3] = 0x7f; return resum(d) }), ErrCorrupt},
+	}
+
+	for _, tt := range tests {
+		_, err := ReadBytecode(bytes.NewReader(tt.data))
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("%s - expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	version := bytes.Clone(good)
+	binary.BigEndian.PutUint16(version[4:6], BYTECODE_VERSION+1)
+	if _, err := ReadBytecode(bytes.NewReader(version)); err == nil {
+		t.Fatal("newer version - expected an error")
+	}
+}
+
+// program builds a script whose chunk is code, every byte on line 1.
+func program(code []byte, constants ...Value) *Function {
+	lines := make([]int, len(code))
+	for n := range lines {
+		lines[n] = 1
+	}
+	return &Function{Chunk: Chunk{Code: code, Lines: lines, Constants: constants}}
+}
+
+func TestBytecodeRejectsBadInstructions(t *testing.T) {
+	op := func(ops ...OpCode) []byte {
+		code := make([]byte, len(ops))
+		for n, o := range ops {
+			code[n] = byte(o)
+		}
+		return code
+	}
+	closure := &Function{Name: "f", UpvalueCount: 1, Chunk: Chunk{Code: op(OP_NIL, OP_RETURN), Lines: []int{1, 1}}}
+
+	tests := []struct {
+		name string
+		code []byte
+		err  string
+	}{
+		{"upvalue", op(OP_GET_UPVALUE, 5, OP_POP, OP_NIL, OP_RETURN), "upvalue 5 out of range"},
+		{"set upvalue", op(OP_NIL, OP_SET_UPVALUE, 0, OP_RETURN), "upvalue 0 out of range"},
+		{"local", op(OP_GET_LOCAL, 3, OP_POP, OP_NIL, OP_RETURN), "local slot 3 out of range"},
+		{"closure upvalue", op(OP_CLOSURE, 0, 0, 2, OP_RETURN), "upvalue 2 out of range"},
+		{"closure local", op(OP_CLOSURE, 0, 1, 9, OP_RETURN), "local slot 9 out of range"},
+		{"closure flag", op(OP_CLOSURE, 0, 7, 0, OP_RETURN), "bad upvalue of a closure"},
+		{"pop", op(OP_POP, OP_POP, OP_NIL, OP_RETURN), "OP_POP pops more than the stack holds"},
+		{"call", op(OP_NIL, OP_CALL, 4, OP_RETURN), "OP_CALL pops more than the stack holds"},
+		{"end", op(OP_NIL, OP_POP), "runs off the end of the chunk"},
+		{"mid instruction", op(OP_JUMP, 0, 1, OP_GET_LOCAL, 0, OP_NIL, OP_RETURN), "jump into the middle of an instruction"},
+		{"heights", op(OP_TRUE, OP_JUMP_IF_FALSE, 0, 1, OP_NIL, OP_NIL, OP_RETURN), "stack height differs"},
+	}
+
+	for _, tt := range tests {
+		data := encode(t, program(tt.code, closure))
+		_, err := ReadBytecode(bytes.NewReader(data))
+		if !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), tt.err) {
+			t.Fatalf("%s - expected an error containing %q, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestInterpretRecoversFromBadBytecode(t *testing.T) {
+	// skips ReadBytecode, so nothing checks the upvalue
+	bad := program([]byte{byte(OP_GET_UPVALUE), 5, byte(OP_PRINT), byte(OP_NIL), byte(OP_RETURN)})
+
+	machine := New()
+	machine.SetOutput(&bytes.Buffer{})
+	err := machine.Interpret(bad)
+	if err == nil || !strings.HasPrefix(err.Error(), "bad bytecode: ") {
+		t.Fatalf("expected a bad bytecode error, got %v", err)
+	}
+
+	if err := machine.Interpret(compileSource(t, `print 1;`)); err != nil {
+		t.Fatalf("expected the vm to run again after the failure, got %v", err)
+	}
+}
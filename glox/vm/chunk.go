@@ -0,0 +1,62 @@
+package vm
+
+type OpCode byte
+
+const (
+	OP_RETURN OpCode = iota
+	OP_CONSTANT
+	OP_NIL
+	OP_TRUE
+	OP_FALSE
+	OP_POP
+	OP_GET_LOCAL
+	OP_SET_LOCAL
+	OP_GET_GLOBAL
+	OP_SET_GLOBAL
+	OP_DEFINE_GLOBAL
+	OP_EQUAL
+	OP_GREATER
+	OP_LESS
+	OP_ADD
+	OP_SUBTRACT
+	OP_MULTIPLY
+	OP_DIVIDE
+	OP_NOT
+	OP_NEGATE
+	OP_PRINT
+	OP_JUMP_IF_FALSE
+	OP_JUMP
+	OP_LOOP
+	OP_CALL
+	OP_CLOSURE
+	OP_GET_UPVALUE
+	OP_SET_UPVALUE
+	OP_CLOSE_UPVALUE
+	OP_CLASS
+	OP_GET_PROPERTY
+	OP_SET_PROPERTY
+	OP_METHOD
+	OP_INVOKE
+	OP_INHERIT
+	OP_GET_SUPER
+	OP_SUPER_INVOKE
+)
+
+// Chunk is a sequence of bytecode with the source line of every byte and
+// the constants its instructions refer to.
+type Chunk struct {
+	Code      []byte
+	Lines     []int
+	Constants []Value
+}
+
+func (c *Chunk) Write(b byte, line int) {
+	c.Code = append(c.Code, b)
+	c.Lines = append(c.Lines, line)
+}
+
+// AddConstant stores value in the constant table and returns its index.
+func (c *Chunk) AddConstant(value Value) int {
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
@@ -0,0 +1,890 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/token"
+)
+
+const UINT8_COUNT = 256
+
+type Precedence int
+
+const (
+	PREC_NONE       Precedence = iota
+	PREC_ASSIGNMENT            // =
+	PREC_OR                    // or
+	PREC_AND                   // and
+	PREC_EQUALITY              // == !=
+	PREC_COMPARISON            // < > <= >=
+	PREC_TERM                  // + -
+	PREC_FACTOR                // * /
+	PREC_UNARY                 // ! -
+	PREC_CALL                  // . ()
+	PREC_PRIMARY
+)
+
+type parseFn func(c *Compiler, canAssign bool)
+
+type parseRule struct {
+	prefix     parseFn
+	infix      parseFn
+	precedence Precedence
+}
+
+var rules map[token.TokenType]parseRule
+
+func init() {
+	rules = map[token.TokenType]parseRule{
+		token.LEFT_PAREN:    {(*Compiler).grouping, (*Compiler).call, PREC_CALL},
+		token.DOT:           {nil, (*Compiler).dot, PREC_CALL},
+		token.MINUS:         {(*Compiler).unary, (*Compiler).binary, PREC_TERM},
+		token.PLUS:          {nil, (*Compiler).binary, PREC_TERM},
+		token.SLASH:         {nil, (*Compiler).binary, PREC_FACTOR},
+		token.STAR:          {nil, (*Compiler).binary, PREC_FACTOR},
+		token.BANG:          {(*Compiler).unary, nil, PREC_NONE},
+		token.BANG_EQUAL:    {nil, (*Compiler).binary, PREC_EQUALITY},
+		token.EQUAL_EQUAL:   {nil, (*Compiler).binary, PREC_EQUALITY},
+		token.GREATER:       {nil, (*Compiler).binary, PREC_COMPARISON},
+		token.GREATER_EQUAL: {nil, (*Compiler).binary, PREC_COMPARISON},
+		token.LESS:          {nil, (*Compiler).binary, PREC_COMPARISON},
+		token.LESS_EQUAL:    {nil, (*Compiler).binary, PREC_COMPARISON},
+		token.IDENTIFIER:    {(*Compiler).variable, nil, PREC_NONE},
+		token.STRING:        {(*Compiler).literal, nil, PREC_NONE},
+		token.NUMBER:        {(*Compiler).literal, nil, PREC_NONE},
+		token.AND:           {nil, (*Compiler).and, PREC_AND},
+		token.OR:            {nil, (*Compiler).or, PREC_OR},
+		token.FALSE:         {(*Compiler).literal, nil, PREC_NONE},
+		token.TRUE:          {(*Compiler).literal, nil, PREC_NONE},
+		token.NIL:           {(*Compiler).literal, nil, PREC_NONE},
+		token.SUPER:         {(*Compiler).super, nil, PREC_NONE},
+		token.THIS:          {(*Compiler).this, nil, PREC_NONE},
+	}
+}
+
+func getRule(tt token.TokenType) parseRule {
+	return rules[tt]
+}
+
+type FunctionType int
+
+const (
+	TYPE_FUNCTION FunctionType = iota
+	TYPE_INITIALIZER
+	TYPE_METHOD
+	TYPE_SCRIPT
+)
+
+type local struct {
+	name       string
+	depth      int
+	isCaptured bool
+}
+
+type upvalue struct {
+	index   byte
+	isLocal bool
+}
+
+// funcCompiler holds the state of the function being compiled; enclosing
+// points at the function it is nested in.
+type funcCompiler struct {
+	enclosing  *funcCompiler
+	function   *Function
+	ftype      FunctionType
+	locals     []local
+	upvalues   []upvalue
+	scopeDepth int
+}
+
+type classCompiler struct {
+	enclosing     *classCompiler
+	hasSuperclass bool
+}
+
+// Compiler turns glox tokens into bytecode in a single pass, the way
+// clox's compiler.c does.
+type Compiler struct {
+	tokens   []token.Token
+	next     int
+	current  *token.Token
+	previous *token.Token
+
+	fn    *funcCompiler
+	class *classCompiler
+
+	errors    []string
+	panicMode bool
+}
+
+// Compile compiles a whole script into the function the VM runs first.
+func Compile(tokens []token.Token) (*Function, error) {
+	c := &Compiler{tokens: tokens}
+	c.beginFunction(TYPE_SCRIPT, "")
+
+	c.advance()
+	for !c.match(token.EOF) {
+		c.declaration()
+	}
+
+	function := c.endFunction()
+	if len(c.errors) > 0 {
+		return nil, errors.New(strings.Join(c.errors, "\n"))
+	}
+	return function, nil
+}
+
+func (c *Compiler) beginFunction(ftype FunctionType, name string) {
+	fc := &funcCompiler{
+		enclosing: c.fn,
+		function:  &Function{Name: name},
+		ftype:     ftype,
+	}
+
+	// slot zero holds the function itself, or the receiver in methods
+	slot := local{depth: 0}
+	if ftype != TYPE_FUNCTION {
+		slot.name = "this"
+	}
+	fc.locals = append(fc.locals, slot)
+	c.fn = fc
+}
+
+func (c *Compiler) endFunction() *Function {
+	c.emitReturn()
+	function := c.fn.function
+	c.fn = c.fn.enclosing
+	return function
+}
+
+func (c *Compiler) currentChunk() *Chunk {
+	return &c.fn.function.Chunk
+}
+
+func (c *Compiler) errorAt(t *token.Token, message string) {
+	if c.panicMode {
+		return
+	}
+	c.panicMode = true
+
+	where := ""
+	switch t.Type {
+	case token.EOF:
+		where = " at end"
+	default:
+		where = fmt.Sprintf(" at '%s'", t.Lexeme)
+	}
+	c.errors = append(c.errors, fmt.Sprintf("[line %d] Error%s: %s", t.Line, where, message))
+}
+
+func (c *Compiler) error(message string) {
+	c.errorAt(c.previous, message)
+}
+
+func (c *Compiler) errorAtCurrent(message string) {
+	c.errorAt(c.current, message)
+}
+
+func (c *Compiler) advance() {
+	c.previous = c.current
+	c.current = &c.tokens[c.next]
+	if c.current.Type != token.EOF {
+		c.next++
+	}
+}
+
+func (c *Compiler) consume(tt token.TokenType, message string) {
+	if c.current.Type == tt {
+		c.advance()
+		return
+	}
+	c.errorAtCurrent(message)
+}
+
+func (c *Compiler) check(tt token.TokenType) bool {
+	return c.current.Type == tt
+}
+
+func (c *Compiler) match(tt token.TokenType) bool {
+	if !c.check(tt) {
+		return false
+	}
+	c.advance()
+	return true
+}
+
+func (c *Compiler) emitByte(b byte) {
+	c.currentChunk().Write(b, c.previous.Line)
+}
+
+func (c *Compiler) emitBytes(bytes ...byte) {
+	for _, b := range bytes {
+		c.emitByte(b)
+	}
+}
+
+func (c *Compiler) emitOp(op OpCode, operands ...byte) {
+	c.emitByte(byte(op))
+	c.emitBytes(operands...)
+}
+
+func (c *Compiler) emitLoop(loopStart int) {
+	c.emitOp(OP_LOOP)
+
+	offset := len(c.currentChunk().Code) - loopStart + 2
+	if offset > 0xffff {
+		c.error("Loop body too large.")
+	}
+	c.emitBytes(byte(offset>>8), byte(offset))
+}
+
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emitOp(op, 0xff, 0xff)
+	return len(c.currentChunk().Code) - 2
+}
+
+func (c *Compiler) emitReturn() {
+	if c.fn.ftype == TYPE_INITIALIZER {
+		c.emitOp(OP_GET_LOCAL, 0)
+	} else {
+		c.emitOp(OP_NIL)
+	}
+	c.emitOp(OP_RETURN)
+}
+
+func (c *Compiler) makeConstant(value Value) byte {
+	constant := c.currentChunk().AddConstant(value)
+	if constant > 0xff {
+		c.error("Too many constants in one chunk.")
+		return 0
+	}
+	return byte(constant)
+}
+
+func (c *Compiler) emitConstant(value Value) {
+	c.emitOp(OP_CONSTANT, c.makeConstant(value))
+}
+
+func (c *Compiler) patchJump(offset int) {
+	// -2 to adjust for the bytecode for the jump offset itself
+	jump := len(c.currentChunk().Code) - offset - 2
+	if jump > 0xffff {
+		c.error("Too much code to jump over.")
+	}
+
+	c.currentChunk().Code[offset] = byte(jump >> 8)
+	c.currentChunk().Code[offset+1] = byte(jump)
+}
+
+func (c *Compiler) beginScope() {
+	c.fn.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	fc := c.fn
+	fc.scopeDepth--
+
+	for len(fc.locals) > 0 && fc.locals[len(fc.locals)-1].depth > fc.scopeDepth {
+		if fc.locals[len(fc.locals)-1].isCaptured {
+			c.emitOp(OP_CLOSE_UPVALUE)
+		} else {
+			c.emitOp(OP_POP)
+		}
+		fc.locals = fc.locals[:len(fc.locals)-1]
+	}
+}
+
+func (c *Compiler) identifierConstant(name *token.Token) byte {
+	return c.makeConstant(name.Lexeme)
+}
+
+func (c *Compiler) resolveLocal(fc *funcCompiler, name string) int {
+	for i := len(fc.locals) - 1; i >= 0; i-- {
+		if fc.locals[i].name == name {
+			if fc.locals[i].depth == -1 {
+				c.error("Can't read local variable in its own initializer.")
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) addUpvalue(fc *funcCompiler, index byte, isLocal bool) int {
+	for i, uv := range fc.upvalues {
+		if uv.index == index && uv.isLocal == isLocal {
+			return i
+		}
+	}
+
+	if len(fc.upvalues) == UINT8_COUNT {
+		c.error("Too many closure variables in function.")
+		return 0
+	}
+
+	fc.upvalues = append(fc.upvalues, upvalue{index: index, isLocal: isLocal})
+	fc.function.UpvalueCount++
+	return len(fc.upvalues) - 1
+}
+
+func (c *Compiler) resolveUpvalue(fc *funcCompiler, name string) int {
+	if fc.enclosing == nil {
+		return -1
+	}
+
+	if local := c.resolveLocal(fc.enclosing, name); local != -1 {
+		fc.enclosing.locals[local].isCaptured = true
+		return c.addUpvalue(fc, byte(local), true)
+	}
+
+	if upvalue := c.resolveUpvalue(fc.enclosing, name); upvalue != -1 {
+		return c.addUpvalue(fc, byte(upvalue), false)
+	}
+
+	return -1
+}
+
+func (c *Compiler) addLocal(name string) {
+	if len(c.fn.locals) == UINT8_COUNT {
+		c.error("Too many local variables in function.")
+		return
+	}
+	c.fn.locals = append(c.fn.locals, local{name: name, depth: -1})
+}
+
+func (c *Compiler) declareVariable() {
+	if c.fn.scopeDepth == 0 {
+		return
+	}
+
+	name := c.previous.Lexeme
+	for i := len(c.fn.locals) - 1; i >= 0; i-- {
+		l := c.fn.locals[i]
+		if l.depth != -1 && l.depth < c.fn.scopeDepth {
+			break
+		}
+		if l.name == name {
+			c.error("Already a variable with this name in this scope.")
+		}
+	}
+
+	c.addLocal(name)
+}
+
+func (c *Compiler) parseVariable(message string) byte {
+	c.consume(token.IDENTIFIER, message)
+
+	c.declareVariable()
+	if c.fn.scopeDepth > 0 {
+		return 0
+	}
+
+	return c.identifierConstant(c.previous)
+}
+
+func (c *Compiler) markInitialized() {
+	if c.fn.scopeDepth == 0 {
+		return
+	}
+	c.fn.locals[len(c.fn.locals)-1].depth = c.fn.scopeDepth
+}
+
+func (c *Compiler) defineVariable(global byte) {
+	if c.fn.scopeDepth > 0 {
+		c.markInitialized()
+		return
+	}
+	c.emitOp(OP_DEFINE_GLOBAL, global)
+}
+
+func (c *Compiler) argumentList() byte {
+	argCount := 0
+	if !c.check(token.RIGHT_PAREN) {
+		for {
+			c.expression()
+			if argCount == 255 {
+				c.error("Can't have more than 255 arguments.")
+			}
+			argCount++
+			if !c.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	c.consume(token.RIGHT_PAREN, "Expect ')' after arguments.")
+	return byte(argCount)
+}
+
+func (c *Compiler) and(canAssign bool) {
+	endJump := c.emitJump(OP_JUMP_IF_FALSE)
+
+	c.emitOp(OP_POP)
+	c.parsePrecedence(PREC_AND)
+
+	c.patchJump(endJump)
+}
+
+func (c *Compiler) or(canAssign bool) {
+	elseJump := c.emitJump(OP_JUMP_IF_FALSE)
+	endJump := c.emitJump(OP_JUMP)
+
+	c.patchJump(elseJump)
+	c.emitOp(OP_POP)
+
+	c.parsePrecedence(PREC_OR)
+	c.patchJump(endJump)
+}
+
+func (c *Compiler) binary(canAssign bool) {
+	operatorType := c.previous.Type
+	rule := getRule(operatorType)
+	c.parsePrecedence(rule.precedence + 1)
+
+	switch operatorType {
+	case token.BANG_EQUAL:
+		c.emitOp(OP_EQUAL)
+		c.emitOp(OP_NOT)
+	case token.EQUAL_EQUAL:
+		c.emitOp(OP_EQUAL)
+	case token.GREATER:
+		c.emitOp(OP_GREATER)
+	case token.GREATER_EQUAL:
+		c.emitOp(OP_LESS)
+		c.emitOp(OP_NOT)
+	case token.LESS:
+		c.emitOp(OP_LESS)
+	case token.LESS_EQUAL:
+		c.emitOp(OP_GREATER)
+		c.emitOp(OP_NOT)
+	case token.PLUS:
+		c.emitOp(OP_ADD)
+	case token.MINUS:
+		c.emitOp(OP_SUBTRACT)
+	case token.STAR:
+		c.emitOp(OP_MULTIPLY)
+	case token.SLASH:
+		c.emitOp(OP_DIVIDE)
+	}
+}
+
+func (c *Compiler) call(canAssign bool) {
+	argCount := c.argumentList()
+	c.emitOp(OP_CALL, argCount)
+}
+
+func (c *Compiler) dot(canAssign bool) {
+	c.consume(token.IDENTIFIER, "Expect property name after '.'.")
+	name := c.identifierConstant(c.previous)
+
+	if canAssign && c.match(token.EQUAL) {
+		c.expression()
+		c.emitOp(OP_SET_PROPERTY, name)
+	} else if c.match(token.LEFT_PAREN) {
+		argCount := c.argumentList()
+		c.emitOp(OP_INVOKE, name, argCount)
+	} else {
+		c.emitOp(OP_GET_PROPERTY, name)
+	}
+}
+
+func (c *Compiler) literal(canAssign bool) {
+	switch c.previous.Type {
+	case token.FALSE:
+		c.emitOp(OP_FALSE)
+	case token.NIL:
+		c.emitOp(OP_NIL)
+	case token.TRUE:
+		c.emitOp(OP_TRUE)
+	case token.NUMBER, token.STRING:
+		c.emitConstant(c.previous.Literal)
+	}
+}
+
+func (c *Compiler) grouping(canAssign bool) {
+	c.expression()
+	c.consume(token.RIGHT_PAREN, "Expect ')' after expression.")
+}
+
+func (c *Compiler) namedVariable(name *token.Token, canAssign bool) {
+	var getOp, setOp OpCode
+	var arg byte
+
+	if local := c.resolveLocal(c.fn, name.Lexeme); local != -1 {
+		arg = byte(local)
+		getOp, setOp = OP_GET_LOCAL, OP_SET_LOCAL
+	} else if upvalue := c.resolveUpvalue(c.fn, name.Lexeme); upvalue != -1 {
+		arg = byte(upvalue)
+		getOp, setOp = OP_GET_UPVALUE, OP_SET_UPVALUE
+	} else {
+		arg = c.identifierConstant(name)
+		getOp, setOp = OP_GET_GLOBAL, OP_SET_GLOBAL
+	}
+
+	if canAssign && c.match(token.EQUAL) {
+		c.expression()
+		c.emitOp(setOp, arg)
+	} else {
+		c.emitOp(getOp, arg)
+	}
+}
+
+func (c *Compiler) variable(canAssign bool) {
+	c.namedVariable(c.previous, canAssign)
+}
+
+func syntheticToken(text string) *token.Token {
+	return &token.Token{Type: token.IDENTIFIER, Lexeme: text}
+}
+
+func (c *Compiler) super(canAssign bool) {
+	if c.class == nil {
+		c.error("Can't use 'super' outside of a class.")
+	} else if !c.class.hasSuperclass {
+		c.error("Can't use 'super' in a class with no superclass.")
+	}
+
+	c.consume(token.DOT, "Expect '.' after 'super'.")
+	c.consume(token.IDENTIFIER, "Expect superclass method name.")
+	name := c.identifierConstant(c.previous)
+
+	c.namedVariable(syntheticToken("this"), false)
+	if c.match(token.LEFT_PAREN) {
+		argCount := c.argumentList()
+		c.namedVariable(syntheticToken("super"), false)
+		c.emitOp(OP_SUPER_INVOKE, name, argCount)
+	} else {
+		c.namedVariable(syntheticToken("super"), false)
+		c.emitOp(OP_GET_SUPER, name)
+	}
+}
+
+func (c *Compiler) this(canAssign bool) {
+	if c.class == nil {
+		c.error("Can't use 'this' outside of a class.")
+		return
+	}
+	c.variable(false)
+}
+
+func (c *Compiler) unary(canAssign bool) {
+	operatorType := c.previous.Type
+
+	c.parsePrecedence(PREC_UNARY)
+
+	switch operatorType {
+	case token.BANG:
+		c.emitOp(OP_NOT)
+	case token.MINUS:
+		c.emitOp(OP_NEGATE)
+	}
+}
+
+func (c *Compiler) parsePrecedence(precedence Precedence) {
+	c.advance()
+	prefixRule := getRule(c.previous.Type).prefix
+	if prefixRule == nil {
+		c.error("Expect expression.")
+		return
+	}
+
+	canAssign := precedence <= PREC_ASSIGNMENT
+	prefixRule(c, canAssign)
+
+	for precedence <= getRule(c.current.Type).precedence {
+		c.advance()
+		infixRule := getRule(c.previous.Type).infix
+		infixRule(c, canAssign)
+	}
+
+	if canAssign && c.match(token.EQUAL) {
+		c.error("Invalid assignment target.")
+	}
+}
+
+func (c *Compiler) expression() {
+	c.parsePrecedence(PREC_ASSIGNMENT)
+}
+
+func (c *Compiler) block() {
+	for !c.check(token.RIGHT_BRACE) && !c.check(token.EOF) {
+		c.declaration()
+	}
+
+	c.consume(token.RIGHT_BRACE, "Expect '}' after block.")
+}
+
+func (c *Compiler) function(ftype FunctionType) {
+	c.beginFunction(ftype, c.previous.Lexeme)
+	c.beginScope()
+
+	c.consume(token.LEFT_PAREN, "Expect '(' after function name.")
+	if !c.check(token.RIGHT_PAREN) {
+		for {
+			c.fn.function.Arity++
+			if c.fn.function.Arity > 255 {
+				c.errorAtCurrent("Can't have more than 255 parameters.")
+			}
+			constant := c.parseVariable("Expect parameter name.")
+			c.defineVariable(constant)
+			if !c.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	c.consume(token.RIGHT_PAREN, "Expect ')' after parameters.")
+	c.consume(token.LEFT_BRACE, "Expect '{' before function body.")
+	c.block()
+
+	upvalues := c.fn.upvalues
+	function := c.endFunction()
+	c.emitOp(OP_CLOSURE, c.makeConstant(function))
+
+	for _, uv := range upvalues {
+		isLocal := byte(0)
+		if uv.isLocal {
+			isLocal = 1
+		}
+		c.emitBytes(isLocal, uv.index)
+	}
+}
+
+func (c *Compiler) method() {
+	c.consume(token.IDENTIFIER, "Expect method name.")
+	constant := c.identifierConstant(c.previous)
+
+	ftype := TYPE_METHOD
+	if c.previous.Lexeme == "init" {
+		ftype = TYPE_INITIALIZER
+	}
+	c.function(ftype)
+	c.emitOp(OP_METHOD, constant)
+}
+
+func (c *Compiler) classDeclaration() {
+	c.consume(token.IDENTIFIER, "Expect class name.")
+	className := c.previous
+	nameConstant := c.identifierConstant(c.previous)
+	c.declareVariable()
+
+	c.emitOp(OP_CLASS, nameConstant)
+	c.defineVariable(nameConstant)
+
+	c.class = &classCompiler{enclosing: c.class}
+
+	if c.match(token.LESS) {
+		c.consume(token.IDENTIFIER, "Expect superclass name.")
+		c.variable(false)
+
+		if className.Lexeme == c.previous.Lexeme {
+			c.error("A class can't inherit from itself.")
+		}
+
+		c.beginScope()
+		c.addLocal("super")
+		c.defineVariable(0)
+
+		c.namedVariable(className, false)
+		c.emitOp(OP_INHERIT)
+		c.class.hasSuperclass = true
+	}
+
+	c.namedVariable(className, false)
+	c.consume(token.LEFT_BRACE, "Expect '{' before class body.")
+	for !c.check(token.RIGHT_BRACE) && !c.check(token.EOF) {
+		c.method()
+	}
+	c.consume(token.RIGHT_BRACE, "Expect '}' after class body.")
+	c.emitOp(OP_POP)
+
+	if c.class.hasSuperclass {
+		c.endScope()
+	}
+
+	c.class = c.class.enclosing
+}
+
+func (c *Compiler) funDeclaration() {
+	global := c.parseVariable("Expect function name.")
+	c.markInitialized()
+	c.function(TYPE_FUNCTION)
+	c.defineVariable(global)
+}
+
+func (c *Compiler) varDeclaration() {
+	global := c.parseVariable("Expect variable name.")
+
+	if c.match(token.EQUAL) {
+		c.expression()
+	} else {
+		c.emitOp(OP_NIL)
+	}
+	c.consume(token.SEMICOLON, "Expect ';' after variable declaration.")
+
+	c.defineVariable(global)
+}
+
+func (c *Compiler) expressionStatement() {
+	c.expression()
+	c.consume(token.SEMICOLON, "Expect ';' after expression.")
+	c.emitOp(OP_POP)
+}
+
+func (c *Compiler) forStatement() {
+	c.beginScope()
+	c.consume(token.LEFT_PAREN, "Expect '(' after 'for'.")
+	if c.match(token.SEMICOLON) {
+		// no initializer
+	} else if c.match(token.VAR) {
+		c.varDeclaration()
+	} else {
+		c.expressionStatement()
+	}
+
+	loopStart := len(c.currentChunk().Code)
+	exitJump := -1
+	if !c.match(token.SEMICOLON) {
+		c.expression()
+		c.consume(token.SEMICOLON, "Expect ';' after loop condition.")
+
+		// jump out of the loop if the condition is false
+		exitJump = c.emitJump(OP_JUMP_IF_FALSE)
+		c.emitOp(OP_POP)
+	}
+
+	if !c.match(token.RIGHT_PAREN) {
+		bodyJump := c.emitJump(OP_JUMP)
+		incrementStart := len(c.currentChunk().Code)
+		c.expression()
+		c.emitOp(OP_POP)
+		c.consume(token.RIGHT_PAREN, "Expect ')' after for clauses.")
+
+		c.emitLoop(loopStart)
+		loopStart = incrementStart
+		c.patchJump(bodyJump)
+	}
+
+	c.statement()
+	c.emitLoop(loopStart)
+
+	if exitJump != -1 {
+		c.patchJump(exitJump)
+		c.emitOp(OP_POP)
+	}
+
+	c.endScope()
+}
+
+func (c *Compiler) ifStatement() {
+	c.consume(token.LEFT_PAREN, "Expect '(' after 'if'.")
+	c.expression()
+	c.consume(token.RIGHT_PAREN, "Expect ')' after condition.")
+
+	thenJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emitOp(OP_POP)
+	c.statement()
+
+	elseJump := c.emitJump(OP_JUMP)
+
+	c.patchJump(thenJump)
+	c.emitOp(OP_POP)
+
+	if c.match(token.ELSE) {
+		c.statement()
+	}
+	c.patchJump(elseJump)
+}
+
+func (c *Compiler) printStatement() {
+	c.expression()
+	c.consume(token.SEMICOLON, "Expect ';' after value.")
+	c.emitOp(OP_PRINT)
+}
+
+func (c *Compiler) returnStatement() {
+	if c.fn.ftype == TYPE_SCRIPT {
+		c.error("Can't return from top-level code.")
+	}
+
+	if c.match(token.SEMICOLON) {
+		c.emitReturn()
+		return
+	}
+
+	if c.fn.ftype == TYPE_INITIALIZER {
+		c.error("Can't return a value from an initializer.")
+	}
+
+	c.expression()
+	c.consume(token.SEMICOLON, "Expect ';' after return value.")
+	c.emitOp(OP_RETURN)
+}
+
+func (c *Compiler) whileStatement() {
+	loopStart := len(c.currentChunk().Code)
+	c.consume(token.LEFT_PAREN, "Expect '(' after 'while'.")
+	c.expression()
+	c.consume(token.RIGHT_PAREN, "Expect ')' after condition.")
+
+	exitJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emitOp(OP_POP)
+	c.statement()
+	c.emitLoop(loopStart)
+
+	c.patchJump(exitJump)
+	c.emitOp(OP_POP)
+}
+
+func (c *Compiler) synchronize() {
+	c.panicMode = false
+
+	for c.current.Type != token.EOF {
+		if c.previous.Type == token.SEMICOLON {
+			return
+		}
+		switch c.current.Type {
+		case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF,
+			token.WHILE, token.PRINT, token.RETURN:
+			return
+		}
+
+		c.advance()
+	}
+}
+
+func (c *Compiler) declaration() {
+	if c.match(token.CLASS) {
+		c.classDeclaration()
+	} else if c.match(token.FUN) {
+		c.funDeclaration()
+	} else if c.match(token.VAR) {
+		c.varDeclaration()
+	} else {
+		c.statement()
+	}
+
+	if c.panicMode {
+		c.synchronize()
+	}
+}
+
+func (c *Compiler) statement() {
+	if c.match(token.PRINT) {
+		c.printStatement()
+	} else if c.match(token.FOR) {
+		c.forStatement()
+	} else if c.match(token.IF) {
+		c.ifStatement()
+	} else if c.match(token.RETURN) {
+		c.returnStatement()
+	} else if c.match(token.WHILE) {
+		c.whileStatement()
+	} else if c.match(token.LEFT_BRACE) {
+		c.beginScope()
+		c.block()
+		c.endScope()
+	} else {
+		c.expressionStatement()
+	}
+}
@@ -0,0 +1,166 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+)
+
+var opNames = [...]string{
+	OP_RETURN:        "OP_RETURN",
+	OP_CONSTANT:      "OP_CONSTANT",
+	OP_NIL:           "OP_NIL",
+	OP_TRUE:          "OP_TRUE",
+	OP_FALSE:         "OP_FALSE",
+	OP_POP:           "OP_POP",
+	OP_GET_LOCAL:     "OP_GET_LOCAL",
+	OP_SET_LOCAL:     "OP_SET_LOCAL",
+	OP_GET_GLOBAL:    "OP_GET_GLOBAL",
+	OP_SET_GLOBAL:    "OP_SET_GLOBAL",
+	OP_DEFINE_GLOBAL: "OP_DEFINE_GLOBAL",
+	OP_EQUAL:         "OP_EQUAL",
+	OP_GREATER:       "OP_GREATER",
+	OP_LESS:          "OP_LESS",
+	OP_ADD:           "OP_ADD",
+	OP_SUBTRACT:      "OP_SUBTRACT",
+	OP_MULTIPLY:      "OP_MULTIPLY",
+	OP_DIVIDE:        "OP_DIVIDE",
+	OP_NOT:           "OP_NOT",
+	OP_NEGATE:        "OP_NEGATE",
+	OP_PRINT:         "OP_PRINT",
+	OP_JUMP_IF_FALSE: "OP_JUMP_IF_FALSE",
+	OP_JUMP:          "OP_JUMP",
+	OP_LOOP:          "OP_LOOP",
+	OP_CALL:          "OP_CALL",
+	OP_CLOSURE:       "OP_CLOSURE",
+	OP_GET_UPVALUE:   "OP_GET_UPVALUE",
+	OP_SET_UPVALUE:   "OP_SET_UPVALUE",
+	OP_CLOSE_UPVALUE: "OP_CLOSE_UPVALUE",
+	OP_CLASS:         "OP_CLASS",
+	OP_GET_PROPERTY:  "OP_GET_PROPERTY",
+	OP_SET_PROPERTY:  "OP_SET_PROPERTY",
+	OP_METHOD:        "OP_METHOD",
+	OP_INVOKE:        "OP_INVOKE",
+	OP_INHERIT:       "OP_INHERIT",
+	OP_GET_SUPER:     "OP_GET_SUPER",
+	OP_SUPER_INVOKE:  "OP_SUPER_INVOKE",
+}
+
+func (op OpCode) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return fmt.Sprintf("OP_UNKNOWN(%d)", byte(op))
+}
+
+// Disassemble writes the instructions of function and, after them, those of
+// every function it defines.
+func Disassemble(w io.Writer, function *Function) {
+	DisassembleChunk(w, &function.Chunk, function.String())
+
+	for _, constant := range function.Chunk.Constants {
+		if f, ok := constant.(*Function); ok {
+			fmt.Fprintln(w)
+			Disassemble(w, f)
+		}
+	}
+}
+
+func DisassembleChunk(w io.Writer, chunk *Chunk, name string) {
+	fmt.Fprintf(w, "== %s ==\n", name)
+	for offset := 0; offset < len(chunk.Code); {
+		offset = DisassembleInstruction(w, chunk, offset)
+	}
+}
+
+// DisassembleInstruction writes the instruction at offset and returns the
+// offset of the next one.
+func DisassembleInstruction(w io.Writer, chunk *Chunk, offset int) int {
+	fmt.Fprintf(w, "%04d ", offset)
+	if offset > 0 && chunk.Lines[offset] == chunk.Lines[offset-1] {
+		fmt.Fprint(w, "   | ")
+	} else {
+		fmt.Fprintf(w, "%4d ", chunk.Lines[offset])
+	}
+
+	instruction := OpCode(chunk.Code[offset])
+	switch instruction {
+	case OP_CONSTANT, OP_GET_GLOBAL, OP_SET_GLOBAL, OP_DEFINE_GLOBAL,
+		OP_CLASS, OP_GET_PROPERTY, OP_SET_PROPERTY, OP_METHOD, OP_GET_SUPER:
+		return constantInstruction(w, instruction, chunk, offset)
+	case OP_GET_LOCAL, OP_SET_LOCAL, OP_GET_UPVALUE, OP_SET_UPVALUE, OP_CALL:
+		return byteInstruction(w, instruction, chunk, offset)
+	case OP_JUMP, OP_JUMP_IF_FALSE:
+		return jumpInstruction(w, instruction, 1, chunk, offset)
+	case OP_LOOP:
+		return jumpInstruction(w, instruction, -1, chunk, offset)
+	case OP_INVOKE, OP_SUPER_INVOKE:
+		return invokeInstruction(w, instruction, chunk, offset)
+	case OP_CLOSURE:
+		return closureInstruction(w, chunk, offset)
+	}
+
+	if int(instruction) >= len(opNames) {
+		fmt.Fprintf(w, "Unknown opcode %d\n", byte(instruction))
+		return offset + 1
+	}
+	return simpleInstruction(w, instruction, offset)
+}
+
+func simpleInstruction(w io.Writer, op OpCode, offset int) int {
+	fmt.Fprintln(w, op)
+	return offset + 1
+}
+
+func constantInstruction(w io.Writer, op OpCode, chunk *Chunk, offset int) int {
+	constant := chunk.Code[offset+1]
+	fmt.Fprintf(w, "%-16s %4d '%s'\n", op, constant, Stringify(chunk.Constants[constant]))
+	return offset + 2
+}
+
+func byteInstruction(w io.Writer, op OpCode, chunk *Chunk, offset int) int {
+	slot := chunk.Code[offset+1]
+	fmt.Fprintf(w, "%-16s %4d\n", op, slot)
+	return offset + 2
+}
+
+func jumpInstruction(w io.Writer, op OpCode, sign int, chunk *Chunk, offset int) int {
+	jump := int(chunk.Code[offset+1])<<8 | int(chunk.Code[offset+2])
+	fmt.Fprintf(w, "%-16s %4d -> %d\n", op, offset, offset+3+sign*jump)
+	return offset + 3
+}
+
+func invokeInstruction(w io.Writer, op OpCode, chunk *Chunk, offset int) int {
+	constant := chunk.Code[offset+1]
+	argCount := chunk.Code[offset+2]
+	fmt.Fprintf(w, "%-16s (%d args) %4d '%s'\n", op, argCount, constant, Stringify(chunk.Constants[constant]))
+	return offset + 3
+}
+
+func closureInstruction(w io.Writer, chunk *Chunk, offset int) int {
+	offset++
+	constant := chunk.Code[offset]
+	offset++
+	fmt.Fprintf(w, "%-16s %4d %s\n", OP_CLOSURE, constant, Stringify(chunk.Constants[constant]))
+
+	function := chunk.Constants[constant].(*Function)
+	for j := 0; j < function.UpvalueCount; j++ {
+		kind := "upvalue"
+		if chunk.Code[offset] == 1 {
+			kind = "local"
+		}
+		fmt.Fprintf(w, "%04d      |                     %s %d\n", offset, kind, chunk.Code[offset+1])
+		offset += 2
+	}
+	return offset
+}
+
+// traceInstruction writes the value stack followed by the instruction about
+// to run in frame.
+func (vm *VM) traceInstruction(frame *CallFrame) {
+	fmt.Fprint(vm.trace, "          ")
+	for _, value := range vm.stack[:vm.stackTop] {
+		fmt.Fprintf(vm.trace, "[ %s ]", Stringify(value))
+	}
+	fmt.Fprintln(vm.trace)
+	DisassembleInstruction(vm.trace, &frame.closure.Function.Chunk, frame.ip)
+}
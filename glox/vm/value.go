@@ -0,0 +1,112 @@
+package vm
+
+import (
+	"fmt"
+)
+
+// Value is a Lox value: nil, bool, float64, string or one of the object
+// types below.
+type Value = any
+
+type Function struct {
+	Arity        int
+	UpvalueCount int
+	Chunk        Chunk
+	Name         string
+}
+
+type NativeFn func(args []Value) Value
+
+type Native struct {
+	Name     string
+	Arity    int
+	Function NativeFn
+}
+
+// Upvalue is a variable captured by a closure. While open it points into
+// the VM stack at slot; once closed it holds the value itself.
+type Upvalue struct {
+	location *Value
+	closed   Value
+	slot     int
+	next     *Upvalue
+}
+
+type Closure struct {
+	Function *Function
+	Upvalues []*Upvalue
+}
+
+type Class struct {
+	Name    string
+	Methods map[string]*Closure
+}
+
+type Instance struct {
+	Class  *Class
+	Fields map[string]Value
+}
+
+type BoundMethod struct {
+	Receiver Value
+	Method   *Closure
+}
+
+func NewClosure(function *Function) *Closure {
+	return &Closure{
+		Function: function,
+		Upvalues: make([]*Upvalue, function.UpvalueCount),
+	}
+}
+
+func NewClass(name string) *Class {
+	return &Class{Name: name, Methods: map[string]*Closure{}}
+}
+
+func NewInstance(class *Class) *Instance {
+	return &Instance{Class: class, Fields: map[string]Value{}}
+}
+
+func isFalsey(value Value) bool {
+	b, ok := value.(bool)
+	return value == nil || (ok && !b)
+}
+
+// valuesEqual compares values as the tree walker does; a method taken from
+// the same receiver twice is bound anew each time but counts as equal.
+func valuesEqual(a Value, b Value) bool {
+	if am, ok := a.(*BoundMethod); ok {
+		bm, ok := b.(*BoundMethod)
+		return ok && am.Method == bm.Method && am.Receiver == bm.Receiver
+	}
+	return a == b
+}
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<script>"
+	}
+	return "<fn " + f.Name + ">"
+}
+
+// Stringify returns the text print shows for value, matching the tree
+// walking interpreter.
+func Stringify(value Value) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case *Function:
+		return v.String()
+	case *Closure:
+		return v.Function.String()
+	case *BoundMethod:
+		return v.Method.Function.String()
+	case *Native:
+		return "<native fn '" + v.Name + "'>"
+	case *Class:
+		return v.Name
+	case *Instance:
+		return v.Class.Name + " instance"
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,509 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	FRAMES_MAX = 256
+	STACK_MAX  = FRAMES_MAX * UINT8_COUNT
+	// how many instructions run between two checks of the vm's context
+	CONTEXT_CHECK_INTERVAL = 1024
+)
+
+// frames shown at each end of a long stack trace, as the tree walker does
+const traceEnds = 10
+
+type CallFrame struct {
+	closure *Closure
+	ip      int
+	// slots is the index of the frame's first stack slot
+	slots int
+}
+
+type VM struct {
+	frames     [FRAMES_MAX]CallFrame
+	frameCount int
+
+	stack    [STACK_MAX]Value
+	stackTop int
+
+	globals      map[string]Value
+	openUpvalues *Upvalue
+	out          io.Writer
+	// trace receives the stack and each instruction before it runs when set
+	trace io.Writer
+	// the script is stopped once ctx is done
+	ctx          context.Context
+	instructions int
+}
+
+func New() *VM {
+	vm := &VM{
+		globals: map[string]Value{},
+		out:     os.Stdout,
+	}
+	vm.DefineNative("clock", 0, clockNative)
+	return vm
+}
+
+// SetOutput sends the output of print statements to w instead of stdout.
+func (vm *VM) SetOutput(w io.Writer) {
+	vm.out = w
+}
+
+// SetTrace makes the vm write its value stack and every instruction to w
+// before running it, like clox's DEBUG_TRACE_EXECUTION.
+func (vm *VM) SetTrace(w io.Writer) {
+	vm.trace = w
+}
+
+// SetContext stops the running script once ctx is cancelled or past its
+// deadline.
+func (vm *VM) SetContext(ctx context.Context) {
+	vm.ctx = ctx
+}
+
+func clockNative(args []Value) Value {
+	return float64(time.Now().UnixMilli())
+}
+
+func (vm *VM) DefineNative(name string, arity int, function NativeFn) {
+	vm.globals[name] = &Native{Name: name, Arity: arity, Function: function}
+}
+
+func (vm *VM) resetStack() {
+	vm.stackTop = 0
+	vm.frameCount = 0
+	vm.openUpvalues = nil
+}
+
+func (vm *VM) push(value Value) {
+	vm.stack[vm.stackTop] = value
+	vm.stackTop++
+}
+
+func (vm *VM) pop() Value {
+	vm.stackTop--
+	return vm.stack[vm.stackTop]
+}
+
+func (vm *VM) peek(distance int) Value {
+	return vm.stack[vm.stackTop-1-distance]
+}
+
+// runtimeError builds the error for a failure in the running program,
+// followed by the line and function of the active frames; of a deep stack
+// only the innermost and outermost are shown.
+func (vm *VM) runtimeError(format string, a ...any) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, format, a...)
+
+	elided := vm.frameCount > 2*traceEnds
+	for i := vm.frameCount - 1; i >= 0; i-- {
+		if elided && i == vm.frameCount-1-traceEnds {
+			fmt.Fprintf(&b, "\n... %d more calls", vm.frameCount-2*traceEnds)
+		}
+		if elided && i < vm.frameCount-traceEnds && i >= traceEnds {
+			continue
+		}
+
+		frame := &vm.frames[i]
+		function := frame.closure.Function
+		line := function.Chunk.Lines[frame.ip-1]
+		if function.Name == "" {
+			fmt.Fprintf(&b, "\n[line %d] in script", line)
+		} else {
+			fmt.Fprintf(&b, "\n[line %d] in %s()", line, function.Name)
+		}
+	}
+
+	vm.resetStack()
+	return errors.New(b.String())
+}
+
+func (vm *VM) call(closure *Closure, argCount int) error {
+	if argCount != closure.Function.Arity {
+		return vm.runtimeError("Expected %d arguments but got %d.", closure.Function.Arity, argCount)
+	}
+
+	if vm.frameCount == FRAMES_MAX {
+		return vm.runtimeError("Stack overflow.")
+	}
+
+	frame := &vm.frames[vm.frameCount]
+	vm.frameCount++
+	frame.closure = closure
+	frame.ip = 0
+	frame.slots = vm.stackTop - argCount - 1
+	return nil
+}
+
+func (vm *VM) callValue(callee Value, argCount int) error {
+	switch c := callee.(type) {
+	case *BoundMethod:
+		vm.stack[vm.stackTop-argCount-1] = c.Receiver
+		return vm.call(c.Method, argCount)
+	case *Class:
+		vm.stack[vm.stackTop-argCount-1] = NewInstance(c)
+		if initializer, ok := c.Methods["init"]; ok {
+			return vm.call(initializer, argCount)
+		} else if argCount != 0 {
+			return vm.runtimeError("Expected 0 arguments but got %d.", argCount)
+		}
+		return nil
+	case *Closure:
+		return vm.call(c, argCount)
+	case *Native:
+		if argCount != c.Arity {
+			return vm.runtimeError("Expected %d arguments but got %d.", c.Arity, argCount)
+		}
+		result := c.Function(vm.stack[vm.stackTop-argCount : vm.stackTop])
+		vm.stackTop -= argCount + 1
+		vm.push(result)
+		return nil
+	}
+	return vm.runtimeError("Can only call functions and classes, not %s.", Stringify(callee))
+}
+
+func (vm *VM) invokeFromClass(class *Class, name string, argCount int) error {
+	method, ok := class.Methods[name]
+	if !ok {
+		return vm.runtimeError("Undefined property '%s'.", name)
+	}
+	return vm.call(method, argCount)
+}
+
+func (vm *VM) invoke(name string, argCount int) error {
+	receiver := vm.peek(argCount)
+
+	instance, ok := receiver.(*Instance)
+	if !ok {
+		return vm.runtimeError("Only instances have methods.")
+	}
+
+	if value, ok := instance.Fields[name]; ok {
+		vm.stack[vm.stackTop-argCount-1] = value
+		return vm.callValue(value, argCount)
+	}
+
+	return vm.invokeFromClass(instance.Class, name, argCount)
+}
+
+func (vm *VM) bindMethod(class *Class, name string) error {
+	method, ok := class.Methods[name]
+	if !ok {
+		return vm.runtimeError("Undefined property '%s'.", name)
+	}
+
+	bound := &BoundMethod{Receiver: vm.peek(0), Method: method}
+	vm.pop()
+	vm.push(bound)
+	return nil
+}
+
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	var prevUpvalue *Upvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.slot > slot {
+		prevUpvalue = upvalue
+		upvalue = upvalue.next
+	}
+
+	if upvalue != nil && upvalue.slot == slot {
+		return upvalue
+	}
+
+	createdUpvalue := &Upvalue{location: &vm.stack[slot], slot: slot, next: upvalue}
+	if prevUpvalue == nil {
+		vm.openUpvalues = createdUpvalue
+	} else {
+		prevUpvalue.next = createdUpvalue
+	}
+
+	return createdUpvalue
+}
+
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		upvalue := vm.openUpvalues
+		upvalue.closed = *upvalue.location
+		upvalue.location = &upvalue.closed
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+func (vm *VM) defineMethod(name string) {
+	method := vm.peek(0).(*Closure)
+	class := vm.peek(1).(*Class)
+	class.Methods[name] = method
+	vm.pop()
+}
+
+// Interpret runs a compiled script; the error returned describes a runtime
+// failure. Bytecode that breaks the vm's assumptions, which ReadBytecode
+// should have rejected, fails the same way instead of crashing.
+func (vm *VM) Interpret(function *Function) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			vm.resetStack()
+			err = fmt.Errorf("bad bytecode: %v", r)
+		}
+	}()
+
+	closure := NewClosure(function)
+	vm.push(closure)
+	if err := vm.call(closure, 0); err != nil {
+		return err
+	}
+
+	return vm.run()
+}
+
+func (vm *VM) run() error {
+	frame := &vm.frames[vm.frameCount-1]
+
+	readByte := func() byte {
+		b := frame.closure.Function.Chunk.Code[frame.ip]
+		frame.ip++
+		return b
+	}
+	readShort := func() int {
+		frame.ip += 2
+		code := frame.closure.Function.Chunk.Code
+		return int(code[frame.ip-2])<<8 | int(code[frame.ip-1])
+	}
+	readConstant := func() Value {
+		return frame.closure.Function.Chunk.Constants[readByte()]
+	}
+	readString := func() string {
+		return readConstant().(string)
+	}
+	numberOperands := func() (float64, float64, bool) {
+		b, bok := vm.peek(0).(float64)
+		a, aok := vm.peek(1).(float64)
+		return a, b, aok && bok
+	}
+
+	for {
+		if vm.trace != nil {
+			vm.traceInstruction(frame)
+		}
+
+		instruction := OpCode(readByte())
+
+		vm.instructions++
+		if vm.ctx != nil && vm.instructions%CONTEXT_CHECK_INTERVAL == 0 {
+			if err := vm.ctx.Err(); err != nil {
+				return vm.runtimeError("execution stopped: %v", err)
+			}
+		}
+
+		switch instruction {
+		case OP_CONSTANT:
+			vm.push(readConstant())
+		case OP_NIL:
+			vm.push(nil)
+		case OP_TRUE:
+			vm.push(true)
+		case OP_FALSE:
+			vm.push(false)
+		case OP_POP:
+			vm.pop()
+		case OP_GET_LOCAL:
+			slot := readByte()
+			vm.push(vm.stack[frame.slots+int(slot)])
+		case OP_SET_LOCAL:
+			slot := readByte()
+			vm.stack[frame.slots+int(slot)] = vm.peek(0)
+		case OP_GET_GLOBAL:
+			name := readString()
+			value, ok := vm.globals[name]
+			if !ok {
+				return vm.runtimeError("Undefined variable '%s'.", name)
+			}
+			vm.push(value)
+		case OP_DEFINE_GLOBAL:
+			name := readString()
+			vm.globals[name] = vm.peek(0)
+			vm.pop()
+		case OP_SET_GLOBAL:
+			name := readString()
+			if _, ok := vm.globals[name]; !ok {
+				return vm.runtimeError("Undefined variable '%s'.", name)
+			}
+			vm.globals[name] = vm.peek(0)
+		case OP_GET_UPVALUE:
+			slot := readByte()
+			vm.push(*frame.closure.Upvalues[slot].location)
+		case OP_SET_UPVALUE:
+			slot := readByte()
+			*frame.closure.Upvalues[slot].location = vm.peek(0)
+		case OP_GET_PROPERTY:
+			instance, ok := vm.peek(0).(*Instance)
+			if !ok {
+				return vm.runtimeError("Only instances have properties.")
+			}
+
+			name := readString()
+			if value, ok := instance.Fields[name]; ok {
+				vm.pop() // instance
+				vm.push(value)
+				break
+			}
+
+			if err := vm.bindMethod(instance.Class, name); err != nil {
+				return err
+			}
+		case OP_SET_PROPERTY:
+			instance, ok := vm.peek(1).(*Instance)
+			if !ok {
+				return vm.runtimeError("Only instances have fields.")
+			}
+
+			instance.Fields[readString()] = vm.peek(0)
+			value := vm.pop()
+			vm.pop()
+			vm.push(value)
+		case OP_GET_SUPER:
+			name := readString()
+			superclass := vm.pop().(*Class)
+
+			if err := vm.bindMethod(superclass, name); err != nil {
+				return err
+			}
+		case OP_EQUAL:
+			b := vm.pop()
+			a := vm.pop()
+			vm.push(valuesEqual(a, b))
+		case OP_GREATER, OP_LESS, OP_SUBTRACT, OP_MULTIPLY, OP_DIVIDE:
+			a, b, ok := numberOperands()
+			if !ok {
+				return vm.runtimeError("Operands must be numbers.")
+			}
+			vm.stackTop -= 2
+
+			switch instruction {
+			case OP_GREATER:
+				vm.push(a > b)
+			case OP_LESS:
+				vm.push(a < b)
+			case OP_SUBTRACT:
+				vm.push(a - b)
+			case OP_MULTIPLY:
+				vm.push(a * b)
+			case OP_DIVIDE:
+				vm.push(a / b)
+			}
+		case OP_ADD:
+			bs, bok := vm.peek(0).(string)
+			as, aok := vm.peek(1).(string)
+			if aok && bok {
+				vm.stackTop -= 2
+				vm.push(as + bs)
+				break
+			}
+
+			a, b, ok := numberOperands()
+			if !ok {
+				return vm.runtimeError("Operands must be two numbers or two strings.")
+			}
+			vm.stackTop -= 2
+			vm.push(a + b)
+		case OP_NOT:
+			vm.push(isFalsey(vm.pop()))
+		case OP_NEGATE:
+			n, ok := vm.peek(0).(float64)
+			if !ok {
+				return vm.runtimeError("Operand must be a number.")
+			}
+			vm.pop()
+			vm.push(-n)
+		case OP_PRINT:
+			fmt.Fprintln(vm.out, Stringify(vm.pop()))
+		case OP_JUMP:
+			offset := readShort()
+			frame.ip += offset
+		case OP_JUMP_IF_FALSE:
+			offset := readShort()
+			if isFalsey(vm.peek(0)) {
+				frame.ip += offset
+			}
+		case OP_LOOP:
+			offset := readShort()
+			frame.ip -= offset
+		case OP_CALL:
+			argCount := int(readByte())
+			if err := vm.callValue(vm.peek(argCount), argCount); err != nil {
+				return err
+			}
+			frame = &vm.frames[vm.frameCount-1]
+		case OP_INVOKE:
+			method := readString()
+			argCount := int(readByte())
+			if err := vm.invoke(method, argCount); err != nil {
+				return err
+			}
+			frame = &vm.frames[vm.frameCount-1]
+		case OP_SUPER_INVOKE:
+			method := readString()
+			argCount := int(readByte())
+			superclass := vm.pop().(*Class)
+			if err := vm.invokeFromClass(superclass, method, argCount); err != nil {
+				return err
+			}
+			frame = &vm.frames[vm.frameCount-1]
+		case OP_CLOSURE:
+			function := readConstant().(*Function)
+			closure := NewClosure(function)
+			vm.push(closure)
+			for i := range closure.Upvalues {
+				isLocal := readByte()
+				index := int(readByte())
+				if isLocal == 1 {
+					closure.Upvalues[i] = vm.captureUpvalue(frame.slots + index)
+				} else {
+					closure.Upvalues[i] = frame.closure.Upvalues[index]
+				}
+			}
+		case OP_CLOSE_UPVALUE:
+			vm.closeUpvalues(vm.stackTop - 1)
+			vm.pop()
+		case OP_RETURN:
+			result := vm.pop()
+			vm.closeUpvalues(frame.slots)
+			vm.frameCount--
+			if vm.frameCount == 0 {
+				vm.pop()
+				return nil
+			}
+
+			vm.stackTop = frame.slots
+			vm.push(result)
+			frame = &vm.frames[vm.frameCount-1]
+		case OP_CLASS:
+			vm.push(NewClass(readString()))
+		case OP_INHERIT:
+			superclass, ok := vm.peek(1).(*Class)
+			if !ok {
+				return vm.runtimeError("Superclass must be a class.")
+			}
+
+			subclass := vm.peek(0).(*Class)
+			for name, method := range superclass.Methods {
+				subclass.Methods[name] = method
+			}
+			vm.pop() // subclass
+		case OP_METHOD:
+			vm.defineMethod(readString())
+		default:
+			return vm.runtimeError("Unknown opcode %d.", instruction)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/parser"
+	"github.com/Martin-Martinez4/crafting-interpreters/glox/scanner"
+)
+
+func runVM(source string) (out string, err error) {
+	s := scanner.NewScanner(source)
+	s.ScanTokens()
+
+	function, err := Compile(s.GetTokens())
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	vm := New()
+	vm.SetOutput(&b)
+	err = vm.Interpret(function)
+	return b.String(), err
+}
+
+func runTreeWalker(source string) (out string, err error) {
+	var b bytes.Buffer
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = b.String(), fmt.Errorf("%v", r)
+		}
+	}()
+
+	s := scanner.NewScanner(source)
+	s.ScanTokens()
+	stmts := parser.NewParser(s.GetTokens()).Parse()
+
+	i := parser.NewInterpreter(stmts, parser.WithOutput(&b))
+	parser.NewResolver(i).ResolveStmts(stmts)
+	i.Interpret(stmts)
+	return b.String(), nil
+}
+
+func expectSameOutput(t *testing.T, name string, source string) {
+	t.Helper()
+
+	expected, treeErr := runTreeWalker(source)
+	got, vmErr := runVM(source)
+
+	if (treeErr == nil) != (vmErr == nil) {
+		t.Fatalf("%s - tree walker error: %v, vm error: %v", name, treeErr, vmErr)
+	}
+	if got != expected {
+		t.Fatalf("%s - output wrong, expected:\n%s\ngot:\n%s", name, expected, got)
+	}
+}
+
+func TestScriptsMatchTreeWalker(t *testing.T) {
+	scripts, err := filepath.Glob("../scripts/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range scripts {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the output of clock changes from run to run
+		if strings.Contains(string(source), "clock()") {
+			continue
+		}
+		expectSameOutput(t, path, string(source))
+	}
+}
+
+func TestProgramsMatchTreeWalker(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"arithmetic", `print 1 + 2 * 3 - 4 / 2; print 10 - 4 - 3; print -(1.5); print !nil; print 1 == 1; print "a" + "b";`},
+		{"precedence", `print 1 < 2 + 3; print 2 * 3 >= 1 + 5; print 4 > 1 - 2 * 2; print 1 + 2 == 3; print 6 - 2 < 5 == true;`},
+		{"closures", `
+fun makeCounter() {
+  var i = 0;
+  fun count() { i = i + 1; return i; }
+  return count;
+}
+var c = makeCounter();
+c(); c();
+print c();
+`},
+		{"shared upvalue", `
+var get; var set;
+{
+  var x = "before";
+  fun g() { return x; }
+  fun s(v) { x = v; }
+  get = g; set = s;
+}
+set("after");
+print get();
+`},
+		{"classes", `
+class Point {
+  init(x, y) { this.x = x; this.y = y; }
+  sum() { return this.x + this.y; }
+}
+var p = Point(1, 2);
+var q = Point(3, 4);
+print p.sum();
+print q.sum();
+print p;
+print Point;
+print p.init(5, 6).sum();
+`},
+		{"inheritance", `
+class A { name() { return "A"; } greet() { return "hi " + this.name(); } }
+class B < A { name() { return "B"; } }
+class C < B { greet() { return super.greet() + "!"; } }
+print C().greet();
+var m = C().greet;
+print m();
+`},
+		{"bound methods", `
+class A { m() {} n() {} }
+class B < A {}
+var a = A();
+var b = B();
+print a.m == a.m;
+print a.m == a.n;
+print a.m == A().m;
+print b.m == b.m;
+print b.m == a.m;
+var m = a.m;
+print m == a.m;
+`},
+		{"control flow", `
+var s = "";
+for (var i = 0; i < 5; i = i + 1) {
+  if (i == 2) s = s + "two"; else s = s + "x";
+}
+print s;
+print nil or "default";
+print false and "never";
+var n = 0;
+while (n < 3) n = n + 1;
+print n;
+`},
+	}
+
+	for _, tt := range tests {
+		expectSameOutput(t, tt.name, tt.source)
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	s := scanner.NewScanner("var a = 1;\nif (a) print -a;\n")
+	s.ScanTokens()
+	function, err := Compile(s.GetTokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `== <script> ==
+0000    1 OP_CONSTANT         1 '1'
+0002    | OP_DEFINE_GLOBAL    0 'a'
+0004    2 OP_GET_GLOBAL       2 'a'
+0006    | OP_JUMP_IF_FALSE    6 -> 17
+0009    | OP_POP
+0010    | OP_GET_GLOBAL       3 'a'
+0012    | OP_NEGATE
+0013    | OP_PRINT
+0014    | OP_JUMP            14 -> 18
+0017    | OP_POP
+0018    3 OP_NIL
+0019    | OP_RETURN
+`
+
+	var b bytes.Buffer
+	Disassemble(&b, function)
+	if b.String() != expected {
+		t.Fatalf("disassembly wrong, expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestContextStopsTheScript(t *testing.T) {
+	s := scanner.NewScanner("while (true) {}")
+	s.ScanTokens()
+	function, err := Compile(s.GetTokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	vm := New()
+	vm.SetContext(ctx)
+	err = vm.Interpret(function)
+	if err == nil || !strings.HasPrefix(err.Error(), "execution stopped: context canceled") {
+		t.Fatalf("expected the script to stop, got %v", err)
+	}
+}
+
+func TestCallingANonFunction(t *testing.T) {
+	tests := []struct {
+		source string
+		value  string
+	}{
+		{`var f; f();`, "nil"},
+		{`var n = 1.5; n(2);`, "1.5"},
+		{`"text"();`, "text"},
+	}
+
+	for _, tt := range tests {
+		_, treeErr := runTreeWalker(tt.source)
+		_, vmErr := runVM(tt.source)
+		for _, err := range []error{treeErr, vmErr} {
+			if err == nil || !strings.Contains(err.Error(), tt.value) || strings.Contains(err.Error(), "%!") {
+				t.Fatalf("%s - expected an error naming %s, got %v", tt.source, tt.value, err)
+			}
+		}
+	}
+}
+
+func TestStackOverflowTraceIsShortened(t *testing.T) {
+	_, err := runVM("fun f(n) {\n  return f(n + 1);\n}\nf(0);\n")
+	if err == nil {
+		t.Fatal("expected a stack overflow")
+	}
+
+	lines := strings.Split(err.Error(), "\n")
+	if lines[0] != "Stack overflow." || len(lines) != 2+2*traceEnds {
+		t.Fatalf("expected the overflow and %d frames around a gap, got:\n%s", 2*traceEnds, err)
+	}
+	if gap := fmt.Sprintf("... %d more calls", FRAMES_MAX-2*traceEnds); lines[1+traceEnds] != gap {
+		t.Fatalf("expected %q after the innermost frames, got %q", gap, lines[1+traceEnds])
+	}
+	if lines[1] != "[line 2] in f()" || lines[len(lines)-1] != "[line 4] in script" {
+		t.Fatalf("expected the innermost call first and the script last, got:\n%s", err)
+	}
+}